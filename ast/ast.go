@@ -160,6 +160,28 @@ func (ie *InfixExp) String() string {
 	return out.String()
 }
 
+// PipeExp passes Left as the first argument of Right: either a call,
+// whose arguments follow it, or any other expression yielding a function.
+type PipeExp struct {
+	Token token.Token
+	Left  Exp
+	Right Exp
+}
+
+func (pe *PipeExp) expNode()             {}
+func (pe *PipeExp) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PipeExp) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pe.Left.String())
+	out.WriteString(" |> ")
+	out.WriteString(pe.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -169,6 +191,14 @@ func (b *Boolean) expNode()             {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (nl *NullLiteral) expNode()             {}
+func (nl *NullLiteral) TokenLiteral() string { return nl.Token.Literal }
+func (nl *NullLiteral) String() string       { return nl.Token.Literal }
+
 type IfExp struct {
 	Token  token.Token
 	Cond   Exp
@@ -212,9 +242,11 @@ func (bs *BlockStmt) String() string {
 }
 
 type FunctionLiteral struct {
-	Token  token.Token
-	Params []*Identifier
-	Body   *BlockStmt
+	Token     token.Token
+	Name      string // set for declarations and literals bound by let
+	Params    []*Identifier
+	Body      *BlockStmt
+	Generator bool
 }
 
 func (fl *FunctionLiteral) expNode()             {}
@@ -236,6 +268,34 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// FunctionStmt declares a named function, `fn name(params) { body }`. It
+// is bound before any other statement of its block runs.
+type FunctionStmt struct {
+	Token    token.Token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStmt) stmtNode()            {}
+func (fs *FunctionStmt) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FunctionStmt) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, param := range fs.Function.Params {
+		params = append(params, param.String())
+	}
+
+	out.WriteString(fs.TokenLiteral() + " ")
+	out.WriteString(fs.Name.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	out.WriteString(fs.Function.Body.String())
+
+	return out.String()
+}
+
 type CallExp struct {
 	Token    token.Token
 	Function Exp
@@ -292,9 +352,10 @@ func (al *ArrayLiteral) String() string {
 }
 
 type IndexExp struct {
-	Token token.Token
-	Left  Exp
-	Index Exp
+	Token    token.Token
+	Left     Exp
+	Index    Exp
+	Optional bool // a?[k]: null when Left is null
 }
 
 func (ie *IndexExp) expNode()             {}
@@ -304,9 +365,338 @@ func (ie *IndexExp) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
 
 	return out.String()
 }
+
+type ImportStmt struct {
+	Token token.Token
+	Path  *StringLiteral
+	Alias *Identifier
+}
+
+func (is *ImportStmt) stmtNode()            {}
+func (is *ImportStmt) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLiteral() + " ")
+	out.WriteString(`"` + is.Path.Value + `"`)
+
+	if is.Alias != nil {
+		out.WriteString(" as ")
+		out.WriteString(is.Alias.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+type ExportStmt struct {
+	Token token.Token
+	Name  *Identifier
+	Decl  Stmt
+}
+
+func (es *ExportStmt) stmtNode()            {}
+func (es *ExportStmt) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStmt) String() string {
+	return es.TokenLiteral() + " " + es.Decl.String()
+}
+
+type SelectorExp struct {
+	Token    token.Token
+	Left     Exp
+	Sel      *Identifier
+	Optional bool // a?.b: null when Left is null
+}
+
+func (se *SelectorExp) expNode()             {}
+func (se *SelectorExp) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectorExp) String() string {
+	if se.Optional {
+		return se.Left.String() + "?." + se.Sel.String()
+	}
+	return se.Left.String() + "." + se.Sel.String()
+}
+
+type HashLiteral struct {
+	Token  token.Token
+	Keys   []Exp
+	Values []Exp
+}
+
+func (hl *HashLiteral) expNode()             {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range hl.Keys {
+		pairs = append(pairs, key.String()+":"+hl.Values[i].String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+type ForStmt struct {
+	Token    token.Token
+	Var      *Identifier
+	Iterable Exp
+	Body     *BlockStmt
+}
+
+func (fs *ForStmt) stmtNode()            {}
+func (fs *ForStmt) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Var.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+type YieldExp struct {
+	Token token.Token
+	Value Exp
+}
+
+func (ye *YieldExp) expNode()             {}
+func (ye *YieldExp) TokenLiteral() string { return ye.Token.Literal }
+func (ye *YieldExp) String() string {
+	return ye.TokenLiteral() + " " + ye.Value.String()
+}
+
+type StructStmt struct {
+	Token  token.Token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStmt) stmtNode()            {}
+func (ss *StructStmt) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStmt) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	out.WriteString(ss.TokenLiteral() + " ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+type WithExp struct {
+	Token  token.Token
+	Left   Exp
+	Names  []*Identifier
+	Values []Exp
+}
+
+func (we *WithExp) expNode()             {}
+func (we *WithExp) TokenLiteral() string { return we.Token.Literal }
+func (we *WithExp) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for i, name := range we.Names {
+		fields = append(fields, name.String()+": "+we.Values[i].String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(we.Left.String())
+	out.WriteString(" with {")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("})")
+
+	return out.String()
+}
+
+type ImplStmt struct {
+	Token token.Token
+	Name  *Identifier
+	Body  *BlockStmt
+}
+
+func (is *ImplStmt) stmtNode()            {}
+func (is *ImplStmt) TokenLiteral() string { return is.Token.Literal }
+func (is *ImplStmt) String() string {
+	return is.TokenLiteral() + " " + is.Name.String() + " { " + is.Body.String() + " }"
+}
+
+type EnumStmt struct {
+	Token    token.Token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+// EnumVariant is one alternative of an enum declaration. Fields is nil
+// for variants declared without parentheses.
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ev *EnumVariant) String() string {
+	if ev.Fields == nil {
+		return ev.Name.String()
+	}
+
+	fields := []string{}
+	for _, f := range ev.Fields {
+		fields = append(fields, f.String())
+	}
+
+	return ev.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+func (es *EnumStmt) stmtNode()            {}
+func (es *EnumStmt) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStmt) String() string {
+	var out bytes.Buffer
+
+	variants := []string{}
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+
+	out.WriteString(es.TokenLiteral() + " ")
+	out.WriteString(es.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(variants, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+type MatchExp struct {
+	Token   token.Token
+	Subject Exp
+	Arms    []*MatchArm
+}
+
+// MatchArm is a single `Pattern => body` case. The pattern names a
+// variant or struct type, either bare or qualified like Shape.Circle or
+// shapes.Shape.Circle, or is `_`; Bindings is nil when the pattern has no
+// parentheses.
+type MatchArm struct {
+	Pattern  Exp // an *Identifier or a chain of *SelectorExp
+	Bindings []*Identifier
+	Body     *BlockStmt
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+
+	if ma.Bindings != nil {
+		bindings := []string{}
+		for _, b := range ma.Bindings {
+			bindings = append(bindings, b.String())
+		}
+		out.WriteString("(" + strings.Join(bindings, ", ") + ")")
+	}
+
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+func (me *MatchExp) expNode()             {}
+func (me *MatchExp) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExp) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, a := range me.Arms {
+		arms = append(arms, a.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// SpawnExp is `spawn f(args)`, which runs the call in a task of its own.
+type SpawnExp struct {
+	Token token.Token
+	Call  *CallExp
+}
+
+func (se *SpawnExp) expNode()             {}
+func (se *SpawnExp) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExp) String() string {
+	return se.TokenLiteral() + " " + se.Call.String()
+}
+
+// SelectExp runs the body of the first of its cases that can proceed,
+// waiting for one unless there is a `_` case.
+type SelectExp struct {
+	Token token.Token
+	Cases []*SelectCase
+}
+
+// SelectCase is a single `recv(ch) as v => body`, `send(ch, v) => body`
+// or `_ => body` case. Comm is nil for `_`, Binding is nil without `as`.
+type SelectCase struct {
+	Comm    *CallExp
+	Binding *Identifier
+	Body    *BlockStmt
+}
+
+func (sc *SelectCase) String() string {
+	var out bytes.Buffer
+
+	if sc.Comm == nil {
+		out.WriteString("_")
+	} else {
+		out.WriteString(sc.Comm.String())
+	}
+
+	if sc.Binding != nil {
+		out.WriteString(" as " + sc.Binding.String())
+	}
+
+	out.WriteString(" => ")
+	out.WriteString(sc.Body.String())
+
+	return out.String()
+}
+
+func (se *SelectExp) expNode()             {}
+func (se *SelectExp) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectExp) String() string {
+	cases := []string{}
+	for _, c := range se.Cases {
+		cases = append(cases, c.String())
+	}
+	return "select { " + strings.Join(cases, ", ") + " }"
+}
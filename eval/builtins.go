@@ -4,20 +4,90 @@ import (
 	"github.com/mdaisuke/monk/obj"
 )
 
-var builtins = map[string]*obj.Builtin{
-	"len": &obj.Builtin{
-		Fn: func(args ...obj.Obj) obj.Obj {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
-					len(args))
-			}
-
-			switch arg := args[0].(type) {
-			case *obj.String:
-				return &obj.Integer{Value: int64(len(arg.Value))}
-			default:
-				return newError("argument to `len` is not supported, got %s", args[0].Type())
-			}
+// builtins is filled in by init because several builtins call back into
+// Eval, which itself looks up builtins.
+var builtins map[string]*obj.Builtin
+
+func init() {
+	builtins = map[string]*obj.Builtin{
+		"len": &obj.Builtin{
+			Fn: func(args ...obj.Obj) obj.Obj {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1",
+						len(args))
+				}
+
+				switch arg := args[0].(type) {
+				case *obj.String:
+					return &obj.Integer{Value: int64(len(arg.Value))}
+				case *obj.Array:
+					return &obj.Integer{Value: int64(len(arg.Elems))}
+				case *obj.Hash:
+					return &obj.Integer{Value: int64(len(arg.Keys))}
+				case *obj.Range:
+					return &obj.Integer{Value: arg.Len()}
+				default:
+					return newError("argument to `len` is not supported, got %s", args[0].Type())
+				}
+			},
+		},
+		"iter": &obj.Builtin{
+			Fn: func(args ...obj.Obj) obj.Obj {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1",
+						len(args))
+				}
+
+				iterator, err := toIterator("iter", args[0])
+				if err != nil {
+					return err
+				}
+				return iterator
+			},
+		},
+		"next": &obj.Builtin{
+			Fn: func(args ...obj.Obj) obj.Obj {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1",
+						len(args))
+				}
+
+				iterator, ok := args[0].(*obj.Iterator)
+				if !ok {
+					return newError("argument to `next` must be ITERATOR, got %s",
+						args[0].Type())
+				}
+
+				elem, ok := iterator.Next()
+				if !ok {
+					return NULL
+				}
+				return elem
+			},
 		},
-	},
+		"exit": &obj.Builtin{
+			Fn: func(args ...obj.Obj) obj.Obj {
+				var code int64
+				if len(args) != 0 {
+					if err := checkArgs("exit", args, obj.INTEGER_OBJ); err != nil {
+						return err
+					}
+					code = args[0].(*obj.Integer).Value
+				}
+				if code < 0 || code > 255 {
+					return newError("exit status must be between 0 and 255, got %d", code)
+				}
+
+				// Exiting is up to the host: the script only unwinds.
+				err := newKindError(obj.EXIT, "exit with status %d", code)
+				err.Code = int(code)
+				return err
+			},
+		},
+	}
+
+	initMethods()
+	initThreadBuiltins()
+	initSizedBuiltins()
+	initRunBuiltins()
 }
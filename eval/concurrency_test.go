@@ -0,0 +1,158 @@
+package eval
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mdaisuke/monk/lexer"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/parser"
+)
+
+const prelude = `
+struct Point { x, y }
+impl Point {
+	fn norm(self) { self.x * self.x + self.y * self.y }
+}
+fn fib(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }
+fn spin() { spin() }
+let origin = Point(0, 0);
+`
+
+func frozenPrelude(t *testing.T) *obj.Env {
+	t.Helper()
+
+	env := obj.NewEnv()
+	if result := Eval(parser.New(lexer.New(prelude)).ParseProgram(), env); isError(result) {
+		t.Fatalf("prelude: %s", result.Inspect())
+	}
+	env.Freeze()
+	return env
+}
+
+func TestFrozenEnvSharedByGoroutines(t *testing.T) {
+	shared := frozenPrelude(t)
+	program := parser.New(lexer.New(`
+	let p = origin with { x: 3, y: 4 };
+	let answer = fib(15) + p.norm();
+	answer
+	`)).ParseProgram()
+
+	var wg sync.WaitGroup
+	results := make([]obj.Obj, 16)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = EvalWithLimits(program, obj.NewEnclosedEnv(shared), Limits{})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		testIntegerObj(t, result, 610+25)
+	}
+	if _, ok := shared.Get("answer"); ok {
+		t.Errorf("binding leaked into the shared env")
+	}
+}
+
+func TestFrozenEnvFunctionsRunUnderCaller(t *testing.T) {
+	shared := frozenPrelude(t)
+	program := parser.New(lexer.New("spin()")).ParseProgram()
+
+	var wg sync.WaitGroup
+	results := make([]obj.Obj, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = EvalWithLimits(program, obj.NewEnclosedEnv(shared), Limits{MaxSteps: 1000 * int64(i+1)})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		testErrorKind(t, "spin()", result, obj.STEP_LIMIT)
+	}
+}
+
+func TestFrozenEnvWrites(t *testing.T) {
+	shared := frozenPrelude(t)
+
+	evaluated := Eval(parser.New(lexer.New("impl Point { fn zero(self) { 0 } }")).ParseProgram(), obj.NewEnclosedEnv(shared))
+	testStringOrErrorObj(t, evaluated, "cannot impl Point: it is bound in a frozen environment")
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1", "cannot bind x: the environment is frozen"},
+		{"fn f() { 1 }", "cannot bind f: the environment is frozen"},
+		{"struct P { x }", "cannot bind P: the environment is frozen"},
+		{"enum E { A }", "cannot bind E: the environment is frozen"},
+		{`import "list"`, "cannot bind list: the environment is frozen"},
+		{"if (true) { let y = 2 }", "cannot bind y: the environment is frozen"},
+		{"let f = fn() { let y = 2; y }; f()", "cannot bind f: the environment is frozen"},
+		{"fib(10)", 55},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := Eval(program, shared)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		}
+	}
+
+	// Runtimes evaluate in an Env of their own on top of a frozen one.
+	for _, input := range []string{"1", "let x = 1; x"} {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		testIntegerObj(t, EvalContext(context.Background(), program, shared), 1)
+		testIntegerObj(t, EvalWithLimits(program, shared, Limits{}), 1)
+	}
+	if _, ok := shared.Get("x"); ok {
+		t.Errorf("x bound in the frozen Env")
+	}
+	testIntegerObj(t, NewRuntime(nil, nil, Limits{}).Apply(context.Background(), shared, builtins["len"], &obj.String{Value: "ab"}), 2)
+
+	if err := shared.Set("x", NULL); !isError(err) {
+		t.Errorf("Set on a frozen Env did not fail. got=%v", err)
+	}
+	if err := shared.Delete("fib"); err == nil {
+		t.Errorf("Delete on a frozen Env did not fail")
+	}
+}
+
+func TestConcurrentImports(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "a.mk"; import "b.mk"; export let sum = a.value + b.value;`,
+		"a.mk":    `import "b.mk"; export let value = b.value + 1;`,
+		"b.mk":    `export let value = 1;`,
+	})
+	loader := NewLoader()
+
+	var wg sync.WaitGroup
+	results := make([]obj.Obj, 16)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = loader.Load(filepath.Join(dir, "main.mk"))
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result != results[0] {
+			t.Fatalf("module loaded more than once")
+		}
+	}
+	testIntegerObj(t, testExport(t, results[0], "sum"), 3)
+}
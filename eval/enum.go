@@ -0,0 +1,174 @@
+package eval
+
+import (
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+// evalEnumStmt binds the enum itself and, for convenience, each of its
+// variants: constructors for variants with fields and the single value
+// for those without. A variant whose name is already bound in the same
+// scope, say by another enum, is an error rather than shadowing it; only
+// a redeclaration of the same enum may rebind its variants.
+func evalEnumStmt(es *ast.EnumStmt, env *obj.Env) obj.Obj {
+	et := &obj.EnumType{
+		Name:    es.Name.Value,
+		Methods: make(map[string]obj.Obj),
+	}
+
+	for _, v := range es.Variants {
+		vt := &obj.VariantType{Enum: et, Name: v.Name.Value}
+
+		if v.Fields == nil {
+			vt.Unit = &obj.Variant{VariantType: vt}
+		} else {
+			vt.Fields = make([]string, len(v.Fields))
+			for i, f := range v.Fields {
+				vt.Fields[i] = f.Value
+			}
+		}
+
+		et.Variants = append(et.Variants, vt)
+	}
+
+	for _, vt := range et.Variants {
+		clash, ok := boundHere(env, vt.Name)
+		if !ok {
+			continue
+		}
+		switch clash := clash.(type) {
+		case *obj.Variant:
+			if clash.VariantType.Enum.Name != et.Name {
+				return newError("variant %s of enum %s clashes with variant %s of enum %s",
+					vt.Name, et.Name, vt.Name, clash.VariantType.Enum.Name)
+			}
+		case *obj.VariantType:
+			if clash.Enum.Name != et.Name {
+				return newError("variant %s of enum %s clashes with variant %s of enum %s",
+					vt.Name, et.Name, vt.Name, clash.Enum.Name)
+			}
+		default:
+			return newError("variant %s of enum %s clashes with %s already bound to %s",
+				vt.Name, et.Name, vt.Name, clash.Type())
+		}
+	}
+
+	if err := env.Set(et.Name, et); isError(err) {
+		return err
+	}
+	for _, vt := range et.Variants {
+		env.Set(vt.Name, vt.Value())
+	}
+
+	return nil
+}
+
+// boundHere returns the value name is bound to in env itself, not in the
+// scopes enclosing it.
+func boundHere(env *obj.Env, name string) (obj.Obj, bool) {
+	if env.Resolve(name) != env {
+		return nil, false
+	}
+	return env.Get(name)
+}
+
+func newVariant(vt *obj.VariantType, args []obj.Obj) obj.Obj {
+	if len(args) != len(vt.Fields) {
+		return newError("wrong number of arguments. got=%d, want=%d",
+			len(args), len(vt.Fields))
+	}
+
+	values := make([]obj.Obj, len(args))
+	copy(values, args)
+
+	return &obj.Variant{VariantType: vt, Values: values}
+}
+
+// evalIsExp reports whether value is an instance of typ, which may be an
+// enum, one of its variants or a struct type.
+func evalIsExp(value, typ obj.Obj) obj.Obj {
+	switch typ := typ.(type) {
+	case *obj.EnumType:
+		v, ok := value.(*obj.Variant)
+		return nativeBoolToBooleanObj(ok && v.VariantType.Enum == typ)
+	case *obj.VariantType:
+		v, ok := value.(*obj.Variant)
+		return nativeBoolToBooleanObj(ok && v.VariantType == typ)
+	case *obj.Variant:
+		if typ.VariantType.Unit == typ {
+			return nativeBoolToBooleanObj(value == typ)
+		}
+	case *obj.StructType:
+		s, ok := value.(*obj.Struct)
+		return nativeBoolToBooleanObj(ok && s.StructType == typ)
+	}
+
+	return newError("unknown op: %s is %s", value.Type(), typ.Type())
+}
+
+func evalMatchExp(me *ast.MatchExp, env *obj.Env) obj.Obj {
+	body, armEnv, err := selectMatchArm(me, env)
+	if err != nil {
+		return err
+	}
+	return Eval(body, armEnv)
+}
+
+// selectMatchArm returns the body of the first arm matching the subject of
+// me, with the Env binding its fields, or the error that stopped matching.
+func selectMatchArm(me *ast.MatchExp, env *obj.Env) (*ast.BlockStmt, *obj.Env, obj.Obj) {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return nil, nil, subject
+	}
+
+	for _, arm := range me.Arms {
+		var values []obj.Obj
+
+		if !isWildcard(arm.Pattern) {
+			pattern := Eval(arm.Pattern, env)
+			if isError(pattern) {
+				return nil, nil, pattern
+			}
+
+			matched := evalIsExp(subject, pattern)
+			if isError(matched) {
+				return nil, nil, newError("invalid pattern %s: %s",
+					arm.Pattern, pattern.Type())
+			}
+			if matched != TRUE {
+				continue
+			}
+
+			switch subject := subject.(type) {
+			case *obj.Variant:
+				values = subject.Values
+			case *obj.Struct:
+				values = subject.Values
+			}
+		}
+
+		armEnv := obj.NewEnclosedEnv(env)
+
+		if arm.Bindings != nil {
+			if len(arm.Bindings) != len(values) {
+				return nil, nil, newError("wrong number of bindings for %s. got=%d, want=%d",
+					arm.Pattern, len(arm.Bindings), len(values))
+			}
+			for i, b := range arm.Bindings {
+				if b.Value != "_" {
+					armEnv.Set(b.Value, values[i])
+				}
+			}
+		}
+
+		return arm.Body, armEnv, nil
+	}
+
+	return nil, nil, newError("no match arm for %s", subject.Inspect())
+}
+
+func isWildcard(pattern ast.Exp) bool {
+	ident, ok := pattern.(*ast.Identifier)
+	return ok && ident.Value == "_"
+}
@@ -0,0 +1,81 @@
+package eval
+
+import (
+	"testing"
+)
+
+func TestEnums(t *testing.T) {
+	shape := "enum Shape { Circle(r), Rect(w, h), Empty }; "
+	area := shape + `let area = fn(s) {
+		match (s) {
+			Circle(r) => 3 * r * r,
+			Rect(w, h) => { w * h }
+			Empty => 0
+		}
+	}; `
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{shape + "Shape", "enum Shape { Circle(r), Rect(w, h), Empty }"},
+		{shape + "Circle", "Shape.Circle(r)"},
+		{shape + "Circle(2)", "Circle(2)"},
+		{shape + "Empty", "Empty"},
+		{shape + "Shape.Rect(1, 2).h", 2},
+		{shape + "Shape.Empty == Empty", true},
+		{shape + "Circle(2) == Circle(2)", true},
+		{shape + "Circle(2) != Circle(3)", true},
+		{shape + "Rect(1, 2) == Circle(1)", false},
+		{shape + "Circle(2) is Shape", true},
+		{shape + "Circle(2) is Circle", true},
+		{shape + "Circle(2) is Rect", false},
+		{shape + "Empty is Empty", true},
+		{shape + "5 is Shape", false},
+		{"struct P { x }; P(1) is P", true},
+		{area + "area(Circle(2))", 12},
+		{area + "area(Rect(2, 3))", 6},
+		{area + "area(Empty)", 0},
+		{shape + "match (Rect(2, 3)) { Circle(r) => r, Rect(_, h) => h }", 3},
+		{shape + "match (Empty) { Circle(r) => r, _ => 7 }", 7},
+		{"struct P { x, y }; match (P(1, 2)) { P(x, y) => x + y }", 3},
+		{shape + "impl Shape { let name = fn(self) { match (self) { Empty => \"empty\", _ => \"full\" } }; }; Empty.name()", "empty"},
+		{shape + "Circle(1, 2)", "wrong number of arguments. got=2, want=1"},
+		{shape + "Circle(2).w", "no field w on variant Circle"},
+		{shape + "Shape.Square", "no variant Square in enum Shape"},
+		{shape + "5 is 5", "unknown op: INTEGER is INTEGER"},
+		{shape + "Circle(2) + Circle(2)", "unknown op: VARIANT + VARIANT"},
+		{shape + "match (Empty) { Circle(r) => r }", "no match arm for Empty"},
+		{shape + "match (Circle(1)) { Circle(a, b) => a }", "wrong number of bindings for Circle. got=2, want=1"},
+		{shape + "let x = 5; match (Empty) { x => 1 }", "invalid pattern x: INTEGER"},
+		{shape + "match (Empty) { Nope => 1 }", "identifier not found: Nope"},
+		{shape + "match (Rect(2, 3)) { Shape.Circle(r) => r, Shape.Rect(w, h) => w * h }", 6},
+		{shape + "match (Empty) { Shape.Circle(r) => r, Shape.Empty => 0 }", 0},
+		{shape + "match (Empty) { Shape.Nope => 1 }", "no variant Nope in enum Shape"},
+		{shape + "match (Circle(1)) { Shape.Circle(a, b) => a }", "wrong number of bindings for Shape.Circle. got=2, want=1"},
+		{"enum A { X, Y }; enum B { Z }; X is A", true},
+		{"enum A { X, Y }; enum B { X, Z }", "variant X of enum B clashes with variant X of enum A"},
+		{"enum A { X(v) }; enum B { X }", "variant X of enum B clashes with variant X of enum A"},
+		{"let X = 1; enum B { X }", "variant X of enum B clashes with X already bound to INTEGER"},
+		{"let X = 1; fn f() { enum B { X }; X is B }; f()", true},
+		{"enum A { X, Y }; enum A { X, Z }; Z is A", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			if isError(evaluated) {
+				testStringOrErrorObj(t, evaluated, expected)
+			} else if evaluated.Inspect() != expected {
+				t.Errorf("wrong inspect. expected=%q, got=%q",
+					expected, evaluated.Inspect())
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package eval
+
+import (
+	"testing"
+)
+
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"a" + "b" == "ab"`, true},
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"ab" > "a"`, true},
+		{`"" < "a"`, true},
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{`[[1, "a"], []] == [[1, "a"], []]`, true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{"null == null", true},
+		{"true == true", true},
+		{"1 == true", false},
+		{`1 == "1"`, false},
+		{"[1] == 1", false},
+		{`import "list"; list.range(0, 3) == list.range(0, 3)`, true},
+		{"struct P { xs }; P([1]) == P([1])", true},
+		{"let f = fn(x) { x }; f == f", true},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{"let f = fn(x) { x }; [f] == [f]", true},
+		{"len == len", true},
+		{`{[1, 2]: "a"}[[1, 2]]`, "a"},
+		{`{[1, [2]]: "a"}[[1, [2]]]`, "a"},
+		{`{[1, 2]: "a"}[[2, 1]]`, nil},
+		{`{null: "n"}[null]`, "n"},
+		{`struct P { x, y }; {P(1, 2): "p"}[P(1, 2)]`, "p"},
+		{`struct P { x }; struct Q { x }; {P(1): "p"}[Q(1)]`, nil},
+		{`enum E { A(x), B }; let h = {A(1): 1, B: 2}; h[A(1)] + h[B]`, 3},
+		{`let h = {[1]: 1, [1]: 2}; len(h)`, 1},
+		{`{"a": 1}.has("a")`, true},
+		{`{[1]: 1}.has([1])`, true},
+		{`{[fn(x) { x }]: 1}`, "unusable as hash key: ARRAY"},
+		{`{{}: 1}`, "unusable as hash key: HASH"},
+		{`{"a": 1}.has(len)`, "unusable as hash key: BUILTIN"},
+		{`"a" - "b"`, "unknown op: STRING - STRING"},
+		{"[1] < [2]", "unknown op: ARRAY < ARRAY"},
+		{`"a" < 1`, "type mismatch: STRING < INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case nil:
+			testNullObj(t, evaluated)
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		}
+	}
+}
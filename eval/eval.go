@@ -34,7 +34,21 @@ func Eval(node ast.Node, env *obj.Env) obj.Obj {
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if err := env.Set(node.Name.Value, val); isError(err) {
+			return err
+		}
+	case *ast.ImportStmt:
+		return evalImportStmt(node, env)
+	case *ast.ExportStmt:
+		return Eval(node.Decl, env)
+	case *ast.ForStmt:
+		return evalForStmt(node, env)
+	case *ast.StructStmt:
+		return evalStructStmt(node, env)
+	case *ast.ImplStmt:
+		return evalImplStmt(node, env)
+	case *ast.EnumStmt:
+		return evalEnumStmt(node, env)
 
 	case *ast.PrefixExp:
 		right := Eval(node.Right, env)
@@ -47,14 +61,26 @@ func Eval(node ast.Node, env *obj.Env) obj.Obj {
 		if isError(left) {
 			return left
 		}
+		if node.Op == "??" {
+			if left != NULL {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExp(node.Op, left, right)
+		result := evalInfixExp(node.Op, left, right)
+		if err := allocate(env, result); err != nil {
+			return err
+		}
+		return result
 	case *ast.IfExp:
 		return evalIfExp(node, env)
 
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.IntegerLiteral:
 		return &obj.Integer{Value: node.Value}
 	case *ast.Boolean:
@@ -62,24 +88,96 @@ func Eval(node ast.Node, env *obj.Env) obj.Obj {
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
-		params := node.Params
-		body := node.Body
-		return &obj.Function{Params: params, Env: env, Body: body}
+		return newFunction(node, env)
+	case *ast.FunctionStmt:
+		// Bound ahead of time by hoistFunctions.
+		return nil
+	case *ast.CallExp, *ast.IndexExp, *ast.SelectorExp:
+		result, _ := evalChain(node.(ast.Exp), env)
+		return result
+	case *ast.StringLiteral:
+		return &obj.String{Value: node.Value}
+	case *ast.ArrayLiteral:
+		elems := evalExps(node.Elems, env)
+		if len(elems) == 1 && isError(elems[0]) {
+			return elems[0]
+		}
+		array := &obj.Array{Elems: elems}
+		if err := allocate(env, array); err != nil {
+			return err
+		}
+		return array
+	case *ast.HashLiteral:
+		hash := evalHashLiteral(node, env)
+		if err := allocate(env, hash); err != nil {
+			return err
+		}
+		return hash
+	case *ast.YieldExp:
+		return evalYieldExp(node, env)
+	case *ast.WithExp:
+		result := evalWithExp(node, env)
+		if err := allocate(env, result); err != nil {
+			return err
+		}
+		return result
+	case *ast.PipeExp:
+		return evalPipeExp(node, env)
+	case *ast.MatchExp:
+		return evalMatchExp(node, env)
+	case *ast.SpawnExp:
+		return evalSpawnExp(node, env)
+	case *ast.SelectExp:
+		return evalSelectExp(node, env)
+	}
+
+	return nil
+}
+
+// evalChain evaluates node, a link of a chain of calls, indexes and
+// selectors, and reports whether an optional link of the chain found its
+// receiver null. The links after it are then skipped, so the whole chain
+// is null: null?.x.y and null?.upper() are null.
+func evalChain(node ast.Exp, env *obj.Env) (obj.Obj, bool) {
+	switch node := node.(type) {
 	case *ast.CallExp:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
+		function, short := evalChain(node.Function, env)
+		if short || isError(function) {
+			return function, short
 		}
 		args := evalExps(node.Args, env)
 		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+			return args[0], false
 		}
-		return applyFunction(function, args)
-	case *ast.StringLiteral:
-		return &obj.String{Value: node.Value}
+		return applyFrom(env, function, args), false
+	case *ast.IndexExp:
+		left, short := evalChain(node.Left, env)
+		if short || isError(left) {
+			return left, short
+		}
+		if node.Optional && left == NULL {
+			return NULL, true
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index, false
+		}
+		return evalIndexExp(left, index), false
+	case *ast.SelectorExp:
+		left, short := evalChain(node.Left, env)
+		if short || isError(left) {
+			return left, short
+		}
+		if node.Optional && left == NULL {
+			return NULL, true
+		}
+		if err := checkSelector(env, left, node.Sel.Value); err != nil {
+			return err, false
+		}
+		return evalSelectorExp(env, left, node.Sel.Value), false
+	default:
+		return Eval(node, env), false
 	}
-
-	return nil
 }
 
 func evalStmts(stmts []ast.Stmt, env *obj.Env) obj.Obj {
@@ -142,12 +240,16 @@ func evalInfixExp(
 	left, right obj.Obj,
 ) obj.Obj {
 	switch {
+	case op == "is":
+		return evalIsExp(left, right)
+	case op == ">>":
+		return evalComposeExp(left, right)
 	case left.Type() == obj.INTEGER_OBJ && right.Type() == obj.INTEGER_OBJ:
 		return evalIntegerInfixExp(op, left, right)
 	case op == "==":
-		return nativeBoolToBooleanObj(left == right)
+		return nativeBoolToBooleanObj(obj.Equal(left, right))
 	case op == "!=":
-		return nativeBoolToBooleanObj(left != right)
+		return nativeBoolToBooleanObj(!obj.Equal(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s",
 			left.Type(), op, right.Type())
@@ -174,6 +276,9 @@ func evalIntegerInfixExp(
 	case "*":
 		return &obj.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &obj.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObj(leftVal < rightVal)
@@ -220,7 +325,22 @@ func isTruthy(o obj.Obj) bool {
 func evalProgram(program *ast.Program, env *obj.Env) obj.Obj {
 	var result obj.Obj
 
+	// Code outside of any Runtime runs as a thread of the global group,
+	// for the tasks it spawns to tell when they deadlock with it.
+	if runOf(env) == nil {
+		global.enter()
+		defer global.exit()
+	}
+
+	if err := hoistFunctions(program.Stmts, env); err != nil {
+		return err
+	}
+
 	for _, stmt := range program.Stmts {
+		if err := step(env); err != nil {
+			return err
+		}
+
 		result = Eval(stmt, env)
 
 		switch result := result.(type) {
@@ -237,7 +357,15 @@ func evalProgram(program *ast.Program, env *obj.Env) obj.Obj {
 func evalBlockStmt(block *ast.BlockStmt, env *obj.Env) obj.Obj {
 	var result obj.Obj
 
+	if err := hoistFunctions(block.Stmts, env); err != nil {
+		return err
+	}
+
 	for _, stmt := range block.Stmts {
+		if err := step(env); err != nil {
+			return err
+		}
+
 		result = Eval(stmt, env)
 
 		if result != nil {
@@ -251,6 +379,32 @@ func evalBlockStmt(block *ast.BlockStmt, env *obj.Env) obj.Obj {
 	return result
 }
 
+// hoistFunctions binds the functions declared among stmts before any of
+// them runs, so that declarations can refer to each other in any order.
+func hoistFunctions(stmts []ast.Stmt, env *obj.Env) obj.Obj {
+	for _, stmt := range stmts {
+		if es, ok := stmt.(*ast.ExportStmt); ok {
+			stmt = es.Decl
+		}
+		if fs, ok := stmt.(*ast.FunctionStmt); ok {
+			if err := env.Set(fs.Name.Value, newFunction(fs.Function, env)); isError(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newFunction(fl *ast.FunctionLiteral, env *obj.Env) *obj.Function {
+	return &obj.Function{
+		Name:      fl.Name,
+		Params:    fl.Params,
+		Body:      fl.Body,
+		Env:       env,
+		Generator: fl.Generator,
+	}
+}
+
 func newError(format string, a ...interface{}) *obj.Error {
 	return &obj.Error{Message: fmt.Sprintf(format, a...)}
 }
@@ -270,11 +424,15 @@ func evalIdentifier(
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	builtin, err := lookupBuiltin(env, node.Value)
+	if err != nil {
+		return err
+	}
+	if builtin != nil {
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return newError("identifier not found: %s", node.Value)
 }
 
 func evalExps(
@@ -297,17 +455,60 @@ func evalExps(
 func applyFunction(fn obj.Obj, args []obj.Obj) obj.Obj {
 	switch fn := fn.(type) {
 	case *obj.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		rt := runOf(fn.Env)
+		return applyUserFunction(fn, args, rt, threadOf(fn.Env, rt))
 	case *obj.Builtin:
 		return fn.Fn(args...)
+	case *obj.StructType:
+		return newStruct(fn, args)
+	case *obj.VariantType:
+		return newVariant(fn, args)
+	case *obj.Composed:
+		result := applyFunction(fn.First, args)
+		if isError(result) {
+			return result
+		}
+		return applyFunction(fn.Then, []obj.Obj{result})
 
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
+// callUserFunction runs fn as a trampoline: a call in tail position of
+// its body comes back as a tailCall and is run by the same loop, so tail
+// recursion does not grow the Go stack. Frames replaced this way are not
+// part of error traces. The body runs on th, and under rt, if not nil,
+// wherever fn was defined.
+func callUserFunction(fn *obj.Function, args []obj.Obj, rt *Runtime, th *thread) obj.Obj {
+	for {
+		if len(args) != len(fn.Params) {
+			return newArityError(fn, len(args))
+		}
+		if fn.Generator {
+			return newGenerator(fn, args, th)
+		}
+
+		extendedEnv := extendFunctionEnv(fn, args)
+		if rt != nil {
+			extendedEnv.Set(runKey, rt)
+		}
+		extendedEnv.Set(threadKey, th)
+		if err := step(extendedEnv); err != nil {
+			return err
+		}
+
+		evaluated := evalTail(fn.Body, extendedEnv, true)
+
+		if tc, ok := evaluated.(*tailCall); ok {
+			fn, args = tc.fn, tc.args
+			continue
+		}
+
+		return addFrame(unwrapReturnValue(evaluated), fn)
+	}
+}
+
 func extendFunctionEnv(
 	fn *obj.Function,
 	args []obj.Obj,
@@ -321,6 +522,83 @@ func extendFunctionEnv(
 	return env
 }
 
+func newArityError(fn *obj.Function, got int) *obj.Error {
+	if fn.Name == "" {
+		return newError("wrong number of arguments. got=%d, want=%d",
+			got, len(fn.Params))
+	}
+	return newError("wrong number of arguments to `%s`. got=%d, want=%d",
+		fn.Name, got, len(fn.Params))
+}
+
+// addFrame records fn in the trace of o if o is an error leaving it.
+func addFrame(o obj.Obj, fn *obj.Function) obj.Obj {
+	err, ok := o.(*obj.Error)
+	if !ok || err == errAbandoned {
+		return o
+	}
+
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	err.Trace = append(err.Trace, name)
+
+	return err
+}
+
+// applyFrom applies fn for code running in env. User functions run under
+// the Runtime of env, so that those shared between evaluations, such as
+// the ones of a frozen Env, are accounted to the evaluation calling them.
+// What builtins and constructors allocate is accounted here.
+func applyFrom(env *obj.Env, fn obj.Obj, args []obj.Obj) obj.Obj {
+	rt := runOf(env)
+	if fn, ok := fn.(*obj.Function); ok {
+		if rt == nil {
+			rt = runOf(fn.Env)
+		}
+		return applyUserFunction(fn, args, rt, threadOf(env, rt))
+	}
+
+	result := applyIn(rt, fn, args)
+	if err := allocate(env, result); err != nil {
+		return err
+	}
+	return result
+}
+
+// applyIn calls fn with args for code running under rt, if not nil, as
+// builtins calling back into scripts do. Its result is not accounted for.
+// The functions composed by >> are called in turn the same way; a user
+// function runs wherever it was defined, as with applyFunction.
+func applyIn(rt *Runtime, fn obj.Obj, args []obj.Obj) obj.Obj {
+	if c, ok := fn.(*obj.Composed); ok && rt != nil {
+		result := applyIn(rt, c.First, args)
+		if isError(result) {
+			return result
+		}
+		return applyIn(rt, c.Then, []obj.Obj{result})
+	}
+
+	if rt != nil {
+		if op, ok := threadBuiltins[asBuiltin(fn)]; ok {
+			return op(rt.group, args)
+		}
+		if err := rt.reserve(asBuiltin(fn), args); err != nil {
+			return err
+		}
+		if op, ok := runBuiltins[asBuiltin(fn)]; ok {
+			return op(rt, args)
+		}
+	}
+	return applyFunction(fn, args)
+}
+
+func asBuiltin(fn obj.Obj) *obj.Builtin {
+	b, _ := fn.(*obj.Builtin)
+	return b
+}
+
 func unwrapReturnValue(o obj.Obj) obj.Obj {
 	if returnValue, ok := o.(*obj.ReturnValue); ok {
 		return returnValue.Value
@@ -333,12 +611,196 @@ func evalStringInfixExp(
 	op string,
 	left, right obj.Obj,
 ) obj.Obj {
-	if op != "+" {
+	leftVal := left.(*obj.String).Value
+	rightVal := right.(*obj.String).Value
+
+	switch op {
+	case "+":
+		return &obj.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObj(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObj(leftVal > rightVal)
+	default:
 		return newError("unknown op: %s %s %s",
 			left.Type(), op, right.Type())
 	}
+}
 
-	leftVal := left.(*obj.String).Value
-	rightVal := right.(*obj.String).Value
-	return &obj.String{Value: leftVal + rightVal}
+func evalImportStmt(is *ast.ImportStmt, env *obj.Env) obj.Obj {
+	loader := Modules
+	if rt := runOf(env); rt != nil {
+		if _, ok := rt.stdlib[is.Path.Value]; ok && !rt.allowsModule(is.Path.Value) {
+			return newKindError(obj.FORBIDDEN, "module %s is not allowed", is.Path.Value)
+		}
+		loader = rt.loader
+	}
+
+	from, _ := env.Get(moduleKey)
+	ld, _ := from.(*load)
+	if ld != nil {
+		loader = ld.loader
+	}
+
+	o := loader.importFrom(ld, is.Path.Value)
+	if isError(o) {
+		return o
+	}
+
+	m := o.(*obj.Module)
+	name := m.Name
+	if is.Alias != nil {
+		name = is.Alias.Value
+	}
+	if err := env.Set(name, m); isError(err) {
+		return err
+	}
+
+	return nil
+}
+
+func evalSelectorExp(env *obj.Env, left obj.Obj, name string) obj.Obj {
+	switch left := left.(type) {
+	case *obj.Module:
+		if val, ok := left.Env.Get(name); ok {
+			return val
+		}
+		return newError("module %s has no export %s", left.Name, name)
+	case *obj.Struct:
+		if val, ok := left.Get(name); ok {
+			return val
+		}
+		if method, ok := lookupMethod(env, left, name); ok {
+			return method
+		}
+		return newError("no field %s on struct %s", name, left.StructType.Name)
+	case *obj.EnumType:
+		if vt, ok := left.Variant(name); ok {
+			return vt.Value()
+		}
+		return newError("no variant %s in enum %s", name, left.Name)
+	case *obj.Variant:
+		if val, ok := left.Get(name); ok {
+			return val
+		}
+		if method, ok := lookupMethod(env, left, name); ok {
+			return method
+		}
+		return newError("no field %s on variant %s", name, left.VariantType.Name)
+	case *obj.Hash:
+		// h.name reads h["name"], falling back to the methods of hashes;
+		// like a missing key, a missing name is null.
+		if val, ok := left.Get(&obj.String{Value: name}); ok {
+			return val
+		}
+		if method, ok := lookupMethod(env, left, name); ok {
+			return method
+		}
+		return NULL
+	default:
+		if method, ok := lookupMethod(env, left, name); ok {
+			return method
+		}
+		return newError("no method %s on %s", name, left.Type())
+	}
+}
+
+func evalIndexExp(left, index obj.Obj) obj.Obj {
+	switch {
+	case left.Type() == obj.ARRAY_OBJ && index.Type() == obj.INTEGER_OBJ:
+		return evalArrayIndexExp(left, index)
+	case left.Type() == obj.HASH_OBJ:
+		return evalHashIndexExp(left, index)
+	default:
+		return newError("index op not supported: %s", left.Type())
+	}
+}
+
+func evalArrayIndexExp(array, index obj.Obj) obj.Obj {
+	elems := array.(*obj.Array).Elems
+	idx := index.(*obj.Integer).Value
+
+	if idx < 0 || idx >= int64(len(elems)) {
+		return NULL
+	}
+
+	return elems[idx]
+}
+
+func evalHashLiteral(
+	node *ast.HashLiteral,
+	env *obj.Env,
+) obj.Obj {
+	hash := obj.NewHash()
+
+	for i, keyNode := range node.Keys {
+		key := Eval(keyNode, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := obj.AsHashable(key)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(node.Values[i], env)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash
+}
+
+func evalHashIndexExp(hash, index obj.Obj) obj.Obj {
+	key, ok := obj.AsHashable(index)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	if value, ok := hash.(*obj.Hash).Get(key); ok {
+		return value
+	}
+
+	return NULL
+}
+
+func evalForStmt(fs *ast.ForStmt, env *obj.Env) obj.Obj {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	it, err := iterate(iterable)
+	if err != nil {
+		return err
+	}
+
+	for {
+		elem, ok := it.Next()
+		if !ok {
+			return nil
+		}
+		if isError(elem) {
+			return elem
+		}
+
+		if err := step(env); err != nil {
+			return err
+		}
+
+		loopEnv := obj.NewEnclosedEnv(env)
+		loopEnv.Set(fs.Var.Value, elem)
+
+		result := Eval(fs.Body, loopEnv)
+		if result != nil {
+			rt := result.Type()
+			if rt == obj.RETURN_VALUE_OBJ || rt == obj.ERROR_OBJ {
+				return result
+			}
+		}
+	}
 }
@@ -232,6 +232,26 @@ func TestErrorHandling(t *testing.T) {
 			`"Hello" - "World"`,
 			"unknown op: STRING - STRING",
 		},
+		{
+			`{"name": "Monkey"}[fn(x) { x }];`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`{fn(x) { x }: 1};`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`for (x in 5) { x }`,
+			"not iterable: INTEGER",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"let n = 0; 10 / n + 1",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -363,6 +383,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
 		{`len(1)`, "argument to `len` is not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 	}
@@ -387,3 +409,152 @@ func TestBuiltinFunctions(t *testing.T) {
 		}
 	}
 }
+
+func TestExit(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode int
+	}{
+		{"exit()", 0},
+		{"exit(3); 5", 3},
+		{"exit(255)", 255},
+		{"fn f() { exit(4) } let x = f(); x", 4},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testErrorKind(t, tt.input, evaluated, obj.EXIT)
+		if err, ok := evaluated.(*obj.Error); ok && err.Code != tt.expectedCode {
+			t.Errorf("%s: wrong exit code. expected=%d, got=%d", tt.input, tt.expectedCode, err.Code)
+		}
+	}
+
+	invalid := []struct {
+		input    string
+		expected string
+	}{
+		{"exit(256)", "exit status must be between 0 and 255, got 256"},
+		{"exit(-1)", "exit status must be between 0 and 255, got -1"},
+		{`exit("1")`, "argument to `exit` must be INTEGER, got STRING"},
+	}
+
+	for _, tt := range invalid {
+		testErrorKind(t, tt.input, testEval(tt.input), "")
+		testStringOrErrorObj(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*obj.Array)
+	if !ok {
+		t.Fatalf("obj is not Array. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elems) != 3 {
+		t.Fatalf("array has wrong num of elems. got=%d",
+			len(result.Elems))
+	}
+
+	testIntegerObj(t, result.Elems[0], 1)
+	testIntegerObj(t, result.Elems[1], 4)
+	testIntegerObj(t, result.Elems[2], 6)
+}
+
+func TestArrayIndexExps(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObj(t, evaluated, int64(integer))
+		} else {
+			testNullObj(t, evaluated)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*obj.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := map[obj.HashKey]int64{
+		(&obj.String{Value: "one"}).HashKey():   1,
+		(&obj.String{Value: "two"}).HashKey():   2,
+		(&obj.String{Value: "three"}).HashKey(): 3,
+		(&obj.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                          5,
+		FALSE.HashKey():                         6,
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+
+		testIntegerObj(t, pair.Value, expectedValue)
+	}
+
+	if result.Inspect() != `{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}` {
+		t.Errorf("hash printed out of insertion order. got=%s", result.Inspect())
+	}
+}
+
+func TestHashIndexExps(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObj(t, evaluated, int64(integer))
+		} else {
+			testNullObj(t, evaluated)
+		}
+	}
+}
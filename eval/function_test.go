@@ -0,0 +1,126 @@
+package eval
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn add(x, y) { x + y } add(1, 2)", 3},
+		{"let r = add(1, 2); fn add(x, y) { x + y } r", 3},
+		{`
+		fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+		fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+		isEven(10)
+		`, true},
+		{`
+		let f = fn() {
+			let r = g();
+			fn g() { h() }
+			fn h() { 7 }
+			r
+		};
+		f()
+		`, 7},
+		{"fn f() { 1 }; let g = f; fn f() { 2 }; g()", 2},
+		{"let f = fn(x) { fn inner() { x * 2 } inner() }; f(4)", 8},
+		{"fn f() { 1 } inner", "identifier not found: inner"},
+		{"struct P { x }; impl P { fn twice(self) { self.x * 2 } }; P(3).twice()", 6},
+		{"fn add(x, y) { x + y } add", "fn add(x, y) {\n(x + y)\n}"},
+		{"let inc = fn(x) { x + 1 }; inc", "fn inc(x) {\n(x + 1)\n}"},
+		{"fn(x) { x }", "fn(x) {\nx\n}"},
+		{"fn add(x, y) { x + y } add(1)", "wrong number of arguments to `add`. got=1, want=2"},
+		{"let inc = fn(x) { x + 1 }; inc(1, 2)", "wrong number of arguments to `inc`. got=2, want=1"},
+		{"fn(x) { x }()", "wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			if isError(evaluated) {
+				testStringOrErrorObj(t, evaluated, expected)
+			} else if evaluated.Inspect() != expected {
+				t.Errorf("wrong inspect. expected=%q, got=%q",
+					expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestErrorTraces(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedTrace []string
+		expected      string
+	}{
+		{
+			"fn inner() { 1 + true } fn outer() { let r = inner(); r } outer()",
+			[]string{"inner", "outer"},
+			"ERROR: type mismatch: INTEGER + BOOLEAN\n    at inner\n    at outer",
+		},
+		{
+			"fn inner() { 1 + true } fn outer() { inner() } outer()",
+			[]string{"inner"},
+			"ERROR: type mismatch: INTEGER + BOOLEAN\n    at inner",
+		},
+		{
+			"let f = fn() { let r = fn(x) { -x }(true); r }; f()",
+			[]string{"<anonymous>", "f"},
+			"ERROR: unknown op: -BOOLEAN\n    at <anonymous>\n    at f",
+		},
+		{
+			"fn down(n) { if (n == 0) { nope } else { 1 + down(n - 1) } } down(3)",
+			[]string{"down", "down", "down", "down"},
+			"ERROR: identifier not found: nope\n    at down (4 times)",
+		},
+		{
+			"fn f(x) { x } f()",
+			nil,
+			"ERROR: wrong number of arguments to `f`. got=0, want=1",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		err, ok := evaluated.(*obj.Error)
+		if !ok {
+			t.Fatalf("obj is not Error. got=%T(%+v)", evaluated, evaluated)
+		}
+
+		if len(err.Trace) != len(tt.expectedTrace) {
+			t.Fatalf("wrong trace. expected=%q, got=%q", tt.expectedTrace, err.Trace)
+		}
+		for i, name := range tt.expectedTrace {
+			if err.Trace[i] != name {
+				t.Errorf("wrong trace. expected=%q, got=%q", tt.expectedTrace, err.Trace)
+			}
+		}
+
+		if err.Inspect() != tt.expected {
+			t.Errorf("wrong inspect. expected=%q, got=%q", tt.expected, err.Inspect())
+		}
+	}
+}
+
+func TestExportFunctionDeclaration(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "lib.mk"; export let result = lib.double(21);`,
+		"lib.mk":  `export fn double(x) { twice(x) } fn twice(x) { x * 2 }`,
+	})
+
+	result := testLoad(t, NewLoader(), filepath.Join(dir, "main.mk"))
+	testIntegerObj(t, testExport(t, result, "result"), 42)
+}
@@ -0,0 +1,147 @@
+package eval
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+// yieldKey binds the running generator's yield function in its call Env.
+// Like the other keys the package binds in Envs, it is not a valid
+// identifier, so scripts cannot refer to it.
+const yieldKey = "<yield>"
+
+// errAbandoned unwinds the body of a generator that was closed, or whose
+// iterator has been garbage collected.
+var errAbandoned = newError("generator abandoned")
+
+// generator runs the body of a generator function on its own goroutine,
+// handing control back and forth with the consumer so that only one side
+// runs at a time.
+type generator struct {
+	yields  chan obj.Obj
+	resume  chan struct{}
+	done    chan struct{} // closed to stop the body
+	stop    func()        // closes done, once
+	start   func()
+	started bool
+	stopped bool
+}
+
+// newGenerator returns the iterator produced by calling the generator
+// function fn from a call on th. The body does not start running until
+// the first Next, and makes its calls on a thread of its own.
+//
+// A suspended generator sits in yield, blocked on the consumer, and may be
+// resumed by a later evaluation. It is stopped when the iterator is closed
+// or becomes unreachable instead of being drained: done is closed, yield
+// returns errAbandoned and the goroutine exits. The last does not happen
+// while the iterator stays reachable from the body itself, as when it is
+// bound in the Env the generator was defined in; such a generator has to
+// be closed.
+func newGenerator(fn *obj.Function, args []obj.Obj, th *thread) obj.Obj {
+	done := make(chan struct{})
+	g := &generator{
+		yields: make(chan obj.Obj),
+		resume: make(chan struct{}),
+		done:   done,
+		stop:   sync.OnceFunc(func() { close(done) }),
+	}
+
+	env := extendFunctionEnv(fn, args)
+	env.Set(threadKey, forkThread(th))
+	env.Set(yieldKey, &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			return g.yield(args[0])
+		},
+	})
+
+	g.start = func() {
+		go g.run(fn.Body, env)
+	}
+
+	iterator := obj.NewIterator(g)
+	runtime.AddCleanup(iterator, func(stop func()) {
+		stop()
+	}, g.stop)
+
+	return iterator
+}
+
+func (g *generator) run(body *ast.BlockStmt, env *obj.Env) {
+	defer close(g.yields)
+
+	result := safely(func() obj.Obj { return Eval(body, env) })
+	if isError(result) && result != errAbandoned {
+		select {
+		case g.yields <- result:
+		case <-g.done:
+		}
+	}
+}
+
+func (g *generator) Next() (obj.Obj, bool) {
+	if g.stopped {
+		return nil, false
+	}
+
+	select {
+	case <-g.done:
+		return newError("generator is closed"), true
+	default:
+	}
+
+	if !g.started {
+		g.started = true
+		g.start()
+	} else {
+		select {
+		case g.resume <- struct{}{}:
+		case <-g.done:
+			return newError("generator is closed"), true
+		}
+	}
+
+	o, ok := <-g.yields
+	if !ok || isError(o) {
+		g.stopped = true
+	}
+	return o, ok
+}
+
+func (g *generator) yield(o obj.Obj) obj.Obj {
+	select {
+	case g.yields <- o:
+	case <-g.done:
+		return errAbandoned
+	}
+
+	select {
+	case <-g.resume:
+		return NULL
+	case <-g.done:
+		return errAbandoned
+	}
+}
+
+// Close stops the body of g where it is suspended. Resuming it afterwards
+// is an error.
+func (g *generator) Close() {
+	g.stop()
+}
+
+func evalYieldExp(ye *ast.YieldExp, env *obj.Env) obj.Obj {
+	yield, ok := env.Get(yieldKey)
+	if !ok {
+		return newError("yield outside of generator")
+	}
+
+	val := Eval(ye.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	return applyFunction(yield, []obj.Obj{val})
+}
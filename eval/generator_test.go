@@ -0,0 +1,154 @@
+package eval
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mdaisuke/monk/lexer"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/parser"
+)
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "seq"; let g = fn() { yield 1; yield 2; }; seq.collect(g())`, []int64{1, 2}},
+		{`import "seq"; let g = fn(xs) { for (x in xs) { yield x * 2 } }; seq.collect(g([1, 2, 3]))`, []int64{2, 4, 6}},
+		{`import "seq"; let g = fn() { yield 1; return 5; yield 2; }; seq.collect(g())`, []int64{1}},
+		{`import "seq"; let g = fn() { if (false) { yield 1 } }; seq.collect(g())`, []int64{}},
+		{`import "seq"; import "list";
+		  let naturals = fn() { for (i in list.range(0, 1000000000)) { yield i } };
+		  seq.collect(seq.take(seq.filter(naturals(), fn(x) { x > 2 }), 3))`, []int64{3, 4, 5}},
+		{`let g = fn() { yield 1; yield 2 }; let it = g(); next(it); next(it)`, 2},
+		{`let g = fn() { yield 1 }; let it = g(); next(it); next(it)`, nil},
+		{`let g = fn() { let x = yield 1; yield x }; let it = g(); next(it); next(it)`, nil},
+		{`let g = fn() { yield 1 }; len(g)`, "argument to `len` is not supported, got FUNCTION"},
+		{`let g = fn() { yield 1 + true }; next(g())`, "type mismatch: INTEGER + BOOLEAN"},
+		{`let g = fn() { yield 1; -true }; let it = g(); next(it); next(it)`, "unknown op: -BOOLEAN"},
+		{`let outer = fn() { let inner = fn() { yield 1 }; inner }; outer()()`, "iterator"},
+		{`let g = fn() { yield 1; yield 2 }; let it = g(); next(it); close(it); next(it)`, "generator is closed"},
+		{`let g = fn() { yield 1 }; let it = g(); close(it); next(it)`, "generator is closed"},
+		{`let g = fn() { 1 + true; yield 1 }; let it = g(); close(it); next(it)`, "generator is closed"},
+		{`let g = fn() { yield 1 }; let it = g(); next(it); next(it); close(it); next(it)`, nil},
+		{`import "seq"; let it = seq.map([1, 2], fn(x) { x }); close(it); next(it)`, nil},
+		{`let g = fn() { yield 1 }; let it = g(); close(it); close(it)`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case nil:
+			testNullObj(t, evaluated)
+		case string:
+			if evaluated.Type() == obj.ITERATOR_OBJ {
+				if evaluated.Inspect() != expected {
+					t.Errorf("wrong inspect. expected=%q, got=%q", expected, evaluated.Inspect())
+				}
+				continue
+			}
+			testStringOrErrorObj(t, evaluated, expected)
+		case []int64:
+			array, ok := evaluated.(*obj.Array)
+			if !ok {
+				t.Errorf("obj is not Array. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elems) != len(expected) {
+				t.Errorf("wrong num of elems. want=%d, got=%d",
+					len(expected), len(array.Elems))
+				continue
+			}
+			for i, e := range expected {
+				testIntegerObj(t, array.Elems[i], e)
+			}
+		}
+	}
+}
+
+func TestAbandonedGeneratorsDoNotLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	input := `
+	import "list";
+	let naturals = fn() { for (i in list.range(0, 1000000000)) { yield i } };
+	let start = fn(n) {
+		if (n > 0) {
+			let it = naturals();
+			next(it);
+			start(n - 1);
+		}
+	};
+	start(50);
+	`
+	testEval(input)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("suspended generators leaked. goroutines before=%d, after=%d",
+				before, runtime.NumGoroutine())
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClosedGeneratorsDoNotLeak(t *testing.T) {
+	src := `
+	import "list";
+	let naturals = fn() { for (i in list.range(0, 1000000000)) { yield i } };
+	let it = naturals();
+	next(it);
+	close(it);
+	`
+
+	tests := []struct {
+		name string
+		eval func() obj.Obj
+	}{
+		{"eval", func() obj.Obj { return testEval(src) }},
+		{"runtime", func() obj.Obj { return testEvalWithLimits(src, Limits{}) }},
+	}
+
+	for _, tt := range tests {
+		before := runtime.NumGoroutine()
+
+		for i := 0; i < 20; i++ {
+			tt.eval()
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: closed generators leaked. goroutines before=%d, after=%d",
+					tt.name, before, runtime.NumGoroutine())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestGeneratorsResumeAcrossEvaluations(t *testing.T) {
+	rt := NewRuntime(nil, nil, Limits{})
+	env := obj.NewEnv()
+	eval := func(input string) obj.Obj {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		return rt.Eval(context.Background(), program, env)
+	}
+
+	testIntegerObj(t, eval(`let g = fn() { yield 1; yield 2 }; let it = g(); next(it)`), 1)
+	testIntegerObj(t, eval(`next(it)`), 2)
+	testNullObj(t, eval(`next(it)`))
+
+	iterator := eval(`g()`)
+	for _, want := range []int64{1, 2} {
+		testIntegerObj(t, rt.Apply(context.Background(), env, rt.builtins["next"], iterator), want)
+	}
+}
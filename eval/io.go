@@ -0,0 +1,60 @@
+package eval
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+// IOBuiltins returns builtins reading from in and writing to out and
+// errOut: puts and warn write their arguments one per line, readline
+// returns the next line of in, or null at its end.
+func IOBuiltins(in io.Reader, out, errOut io.Writer) map[string]*obj.Builtin {
+	// The builtins are shared by the forks of an interpreter, which may
+	// read lines at once.
+	var mu sync.Mutex
+	reader := bufio.NewReader(in)
+
+	return map[string]*obj.Builtin{
+		"puts": &obj.Builtin{
+			Fn: func(args ...obj.Obj) obj.Obj {
+				return writeLines(out, args)
+			},
+		},
+		"warn": &obj.Builtin{
+			Fn: func(args ...obj.Obj) obj.Obj {
+				return writeLines(errOut, args)
+			},
+		},
+		"readline": &obj.Builtin{
+			Fn: func(args ...obj.Obj) obj.Obj {
+				if err := checkArgs("readline", args); err != nil {
+					return err
+				}
+
+				mu.Lock()
+				line, err := reader.ReadString('\n')
+				mu.Unlock()
+				if err != nil && line == "" {
+					if err == io.EOF {
+						return NULL
+					}
+					return newError("could not read line: %s", err)
+				}
+				return &obj.String{Value: strings.TrimRight(line, "\r\n")}
+			},
+		},
+	}
+}
+
+func writeLines(w io.Writer, args []obj.Obj) obj.Obj {
+	for _, arg := range args {
+		if _, err := io.WriteString(w, arg.Inspect()+"\n"); err != nil {
+			return newError("could not write: %s", err)
+		}
+	}
+	return NULL
+}
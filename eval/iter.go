@@ -0,0 +1,85 @@
+package eval
+
+import (
+	"github.com/mdaisuke/monk/obj"
+)
+
+// iterate returns an Iter over o. Besides the obj.Iterable types, a hash
+// with a callable "next" entry acts as a user-defined iterator: next is
+// called with no arguments until it returns null.
+func iterate(o obj.Obj) (obj.Iter, *obj.Error) {
+	if next, ok := userNext(o); ok {
+		return obj.IterFunc(func() (obj.Obj, bool) {
+			elem := applyFunction(next, []obj.Obj{})
+			if elem == NULL {
+				return nil, false
+			}
+			return elem, true
+		}), nil
+	}
+
+	if iterable, ok := o.(obj.Iterable); ok {
+		return iterable.Iter(), nil
+	}
+
+	return nil, newError("not iterable: %s", o.Type())
+}
+
+func userNext(o obj.Obj) (obj.Obj, bool) {
+	hash, ok := o.(*obj.Hash)
+	if !ok {
+		return nil, false
+	}
+
+	next, ok := hash.Get(&obj.String{Value: "next"})
+	if !ok {
+		return nil, false
+	}
+
+	switch next.(type) {
+	case *obj.Function, *obj.Builtin:
+		return next, true
+	default:
+		return nil, false
+	}
+}
+
+// collect drains the iterable o into a slice, counting a step of rt, if
+// not nil, for each element. name is the builtin that asked for it and
+// appears in the error for non-iterable values.
+func collect(rt *Runtime, name string, o obj.Obj) ([]obj.Obj, *obj.Error) {
+	it, err := iterate(o)
+	if err != nil {
+		return nil, newError("argument to `%s` must be ITERABLE, got %s",
+			name, o.Type())
+	}
+
+	elems := []obj.Obj{}
+	for {
+		elem, ok := it.Next()
+		if !ok {
+			return elems, nil
+		}
+		if errObj, ok := elem.(*obj.Error); ok {
+			return nil, errObj
+		}
+		if err := rt.step(); err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+}
+
+// toIterator wraps o in a lazy obj.Iterator, reusing o if it already is one.
+func toIterator(name string, o obj.Obj) (*obj.Iterator, *obj.Error) {
+	if iterator, ok := o.(*obj.Iterator); ok {
+		return iterator, nil
+	}
+
+	it, err := iterate(o)
+	if err != nil {
+		return nil, newError("argument to `%s` must be ITERABLE, got %s",
+			name, o.Type())
+	}
+	return obj.NewIterator(it), nil
+}
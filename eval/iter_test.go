@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+func TestForInLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10 } } }; f()`, 20},
+		{`let f = fn() { for (c in "abc") { if (c == c) { return c } } }; f()`, "a"},
+		{`let f = fn() { for (k in {"a": 1, "b": 2}) { return k } }; f()`, "a"},
+		{`import "list"; let f = fn() { for (i in list.range(5, 10)) { return i } }; f()`, 5},
+		{`let f = fn() { for (x in [1, true]) { x + 1 } }; f()`, "type mismatch: BOOLEAN + INTEGER"},
+		{`for (x in []) { x }`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		case nil:
+			if evaluated != nil {
+				t.Errorf("obj is not nil. got=%T(%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestIterBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let it = iter([1, 2]); next(it); next(it)`, 2},
+		{`let it = iter([1]); next(it); next(it)`, nil},
+		{`let it = iter("hé"); next(it); next(it)`, "é"},
+		{`import "list"; let it = iter(list.range(3)); next(it); next(it)`, 1},
+		{`let it = iter([1]); iter(it) == it`, true},
+		{`iter(1)`, "argument to `iter` must be ITERABLE, got INTEGER"},
+		{`next([1])`, "argument to `next` must be ITERATOR, got ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		case nil:
+			testNullObj(t, evaluated)
+		}
+	}
+}
+
+func TestLazySeq(t *testing.T) {
+	// The user-defined iterator below never ends and the range is huge, so
+	// these only terminate if nothing along the chain builds an array.
+	tests := []struct {
+		input    string
+		expected []int64
+	}{
+		{`import "seq"; seq.collect(seq.take(seq.map({"next": fn() { 7 }}, fn(x) { x * 2 }), 3))`,
+			[]int64{14, 14, 14}},
+		{`import "seq"; import "list";
+		  seq.collect(seq.take(seq.filter(list.range(0, 1000000000), fn(x) { x / 2 * 2 == x }), 3))`,
+			[]int64{0, 2, 4}},
+		{`import "seq"; import "list"; seq.collect(seq.skip(list.range(5), 3))`,
+			[]int64{3, 4}},
+		{`import "seq"; seq.collect(seq.skip([1], 3))`,
+			[]int64{}},
+		{`import "seq"; import "list"; list.map(seq.take([1, 2, 3], 2), fn(x) { x + 1 })`,
+			[]int64{2, 3}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		array, ok := evaluated.(*obj.Array)
+		if !ok {
+			t.Errorf("obj is not Array. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if len(array.Elems) != len(tt.expected) {
+			t.Errorf("wrong num of elems. want=%d, got=%d",
+				len(tt.expected), len(array.Elems))
+			continue
+		}
+		for i, e := range tt.expected {
+			testIntegerObj(t, array.Elems[i], e)
+		}
+	}
+}
+
+func TestSeqErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "seq"; seq.collect(seq.map([1, true], fn(x) { x + 1 }))`, "type mismatch: BOOLEAN + INTEGER"},
+		{`import "seq"; seq.collect(seq.filter([1], fn(x) { -true }))`, "unknown op: -BOOLEAN"},
+		{`import "seq"; seq.take(1, 2)`, "argument to `take` must be ITERABLE, got INTEGER"},
+		{`import "seq"; seq.skip([1], "a")`, "argument to `skip` must be INTEGER, got STRING"},
+		{`let f = fn() { for (x in {"next": fn() { 1 + true }}) { x } }; f()`, "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		testStringOrErrorObj(t, testEval(tt.input), tt.expected)
+	}
+}
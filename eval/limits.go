@@ -0,0 +1,528 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+// Limits bounds the resources a script run by EvalWithLimits may use. A
+// zero field means no limit.
+type Limits struct {
+	// MaxSteps bounds the statements run, calls made and loop iterations.
+	MaxSteps int64
+	// MaxTime bounds the wall time of the evaluation.
+	MaxTime time.Duration
+	// MaxDepth bounds the nesting of function calls, like the package-wide
+	// MaxDepth which applies when it is zero.
+	MaxDepth int
+	// MaxAllocBytes bounds the approximate size of the strings, arrays
+	// and hashes created.
+	MaxAllocBytes int64
+	// Builtins, when not nil, lists the only builtins scripts may use:
+	// names like "len", stdlib members like "strings.split" and whole
+	// stdlib modules like "list". Methods are allowed when the builtin
+	// behind them is.
+	Builtins []string
+}
+
+// runKey binds the Runtime an evaluation belongs to in its Env.
+const runKey = "<run>"
+
+// Runtime is what the evaluations it runs share beyond an Env: the
+// builtins identifiers fall back to, with the methods and stdlib modules
+// made of them, the loader imports go through and the limits each
+// evaluation is held to. Code finds its Runtime through the Env it runs
+// in, so functions defined by a script, including those of the modules it
+// imports, stay accountable to it when called from builtins. A Runtime
+// runs one evaluation at a time.
+type Runtime struct {
+	builtins map[string]*obj.Builtin
+	methods  map[obj.ObjType]map[string]*obj.Builtin
+	stdlib   map[string]*obj.Module
+	loader   *Loader
+	limits   Limits
+	allowed  map[string]bool
+
+	active   int      // evaluations under way, nested ones included
+	env      *obj.Env // where the outermost one bound rt
+	ctx      context.Context
+	cancel   context.CancelFunc
+	deadline time.Time
+	group    *group  // the threads of the evaluation, spawned tasks included
+	thread   *thread // the thread started by the host
+	steps    atomic.Int64
+	alloc    atomic.Int64
+}
+
+func (rt *Runtime) Type() obj.ObjType { return "RUNTIME" }
+func (rt *Runtime) Inspect() string   { return "runtime" }
+
+// NewRuntime returns a Runtime whose identifiers fall back to builtins,
+// the package's own when nil, and whose imports go through loader, a new
+// Loader on the search path of Modules when nil. The loader must not be
+// shared with another Runtime: the modules it loads belong to this one.
+//
+// The stdlib modules and methods scripts can use are made of builtins
+// too: a module holds the builtins named after it, like "strings.split",
+// and a method is there when the builtin behind it is, so a set without
+// "len" has no len method.
+func NewRuntime(builtins map[string]*obj.Builtin, loader *Loader, limits Limits) *Runtime {
+	if builtins == nil {
+		builtins = DefaultBuiltins()
+	}
+	if loader == nil {
+		loader = NewLoader(Modules.SearchPath...)
+	}
+
+	rt := &Runtime{
+		builtins: builtins,
+		methods:  methodsFor(builtins),
+		stdlib:   stdlibFor(builtins),
+		loader:   loader,
+		limits:   limits,
+		group:    newGroup(),
+		thread:   &thread{},
+	}
+	loader.runtime = rt
+
+	if limits.Builtins != nil {
+		rt.allowed = make(map[string]bool)
+		for _, name := range limits.Builtins {
+			rt.allowed[name] = true
+		}
+	}
+
+	return rt
+}
+
+// DefaultBuiltins returns a copy of the builtins Eval falls back to, and
+// of the members of the stdlib modules under names like "strings.split",
+// for hosts to build their own set from.
+func DefaultBuiltins() map[string]*obj.Builtin {
+	all := mergeBuiltins(builtins)
+	for mod, fns := range stdlibBuiltins {
+		for name, fn := range fns {
+			all[mod+"."+name] = fn
+		}
+	}
+	return all
+}
+
+// EvalWithLimits evaluates node in env like Eval, failing with an error of
+// one of the limit kinds as soon as the script exceeds limits. Modules
+// imported by the script are loaded anew, under the same limits.
+func EvalWithLimits(node ast.Node, env *obj.Env, limits Limits) obj.Obj {
+	return NewRuntime(nil, nil, limits).Eval(context.Background(), node, env)
+}
+
+// EvalContext evaluates node in env like Eval, giving up with a CANCELED
+// error at the next call or loop iteration once ctx is done.
+func EvalContext(ctx context.Context, node ast.Node, env *obj.Env) obj.Obj {
+	return NewRuntime(nil, nil, Limits{}).Eval(ctx, node, env)
+}
+
+// Eval evaluates node in env under rt. The limits apply afresh to each
+// evaluation. A frozen env is left as it is: the evaluation makes its
+// bindings in an Env of its own enclosing it.
+func (rt *Runtime) Eval(ctx context.Context, node ast.Node, env *obj.Env) obj.Obj {
+	if env.Frozen() {
+		env = obj.NewEnclosedEnv(env)
+	}
+	if err := rt.enter(ctx, env); err != nil {
+		return err
+	}
+	defer rt.exit()
+
+	return safely(func() obj.Obj { return Eval(node, env) })
+}
+
+// Apply calls fn, a function or builtin, with args under rt, as an
+// evaluation of its own in env, which, like that of Eval, may be frozen.
+func (rt *Runtime) Apply(ctx context.Context, env *obj.Env, fn obj.Obj, args ...obj.Obj) obj.Obj {
+	if env.Frozen() {
+		env = obj.NewEnclosedEnv(env)
+	}
+	if err := rt.enter(ctx, env); err != nil {
+		return err
+	}
+	defer rt.exit()
+
+	return safely(func() obj.Obj { return unwrapReturnValue(applyFrom(env, fn, args)) })
+}
+
+// enter starts an evaluation under ctx in env. An evaluation started while
+// another is under way, as when a builtin calls back into the host, is
+// part of it: it shares its budget and context.
+func (rt *Runtime) enter(ctx context.Context, env *obj.Env) *obj.Error {
+	if err := ctx.Err(); err != nil {
+		return newKindError(obj.CANCELED, "evaluation canceled: %s", err)
+	}
+
+	rt.active++
+	if rt.active > 1 {
+		return nil
+	}
+
+	rt.ctx, rt.cancel = context.WithCancel(ctx)
+	rt.steps.Store(0)
+	rt.alloc.Store(0)
+	rt.deadline = time.Time{}
+	if rt.limits.MaxTime > 0 {
+		rt.deadline = time.Now().Add(rt.limits.MaxTime)
+	}
+
+	env.Set(runKey, rt)
+	rt.env = env
+	rt.group.ctx = rt.ctx
+	rt.group.enter()
+	return nil
+}
+
+// exit ends an evaluation. Once the outermost one is over, the tasks it
+// spawned that are still running are canceled and waited for: none
+// outlives the evaluation.
+func (rt *Runtime) exit() {
+	rt.active--
+	if rt.active > 0 {
+		return
+	}
+
+	rt.group.exit()
+	rt.cancel()
+	rt.group.tasks.Wait()
+
+	rt.env.Delete(runKey)
+	rt.env = nil
+}
+
+// lookupBuiltin returns the builtin an identifier called name falls back
+// to in env.
+func lookupBuiltin(env *obj.Env, name string) (*obj.Builtin, *obj.Error) {
+	rt := runOf(env)
+	if rt == nil {
+		return builtins[name], nil
+	}
+
+	builtin := rt.builtins[name]
+	if builtin != nil && !rt.allows(name, builtin) {
+		return nil, newKindError(obj.FORBIDDEN, "builtin %s is not allowed", name)
+	}
+	return builtin, nil
+}
+
+func runOf(env *obj.Env) *Runtime {
+	if o, ok := env.Get(runKey); ok {
+		return o.(*Runtime)
+	}
+	return nil
+}
+
+func newKindError(kind obj.ErrorKind, format string, a ...interface{}) *obj.Error {
+	err := newError(format, a...)
+	err.Kind = kind
+	return err
+}
+
+// step counts one unit of work done in env against the limits of its
+// Runtime.
+func step(env *obj.Env) *obj.Error {
+	return runOf(env).step()
+}
+
+// step counts one unit of work against the limits of rt, if not nil.
+func (rt *Runtime) step() *obj.Error {
+	if rt == nil {
+		return nil
+	}
+
+	steps := rt.steps.Add(1)
+	if rt.limits.MaxSteps > 0 && steps > rt.limits.MaxSteps {
+		return newKindError(obj.STEP_LIMIT, "step limit exceeded")
+	}
+
+	select {
+	case <-rt.ctx.Done():
+		return newKindError(obj.CANCELED, "evaluation canceled: %s", rt.ctx.Err())
+	default:
+	}
+
+	// Reading the clock costs more than a step; look only now and then.
+	if !rt.deadline.IsZero() && steps%64 == 0 && time.Now().After(rt.deadline) {
+		return newKindError(obj.TIME_LIMIT, "time limit exceeded")
+	}
+
+	return nil
+}
+
+// allocate accounts for o, just created in env, against the limits of its
+// Runtime. Only the object itself counts, its elements were accounted for
+// when they were created.
+func allocate(env *obj.Env, o obj.Obj) *obj.Error {
+	rt := runOf(env)
+	if rt == nil || rt.limits.MaxAllocBytes <= 0 {
+		return nil
+	}
+
+	return rt.allocate(sizeOf(o))
+}
+
+func (rt *Runtime) allocate(size int64) *obj.Error {
+	if rt.alloc.Add(size) > rt.limits.MaxAllocBytes {
+		return newKindError(obj.ALLOC_LIMIT, "allocation limit exceeded")
+	}
+	return nil
+}
+
+// reserve fails if calling fn, a builtin of the package, with args would
+// take the allocations of rt past its limit. It is checked before the call
+// for the builtins whose result grows with their arguments, such as
+// strings.repeat, which could otherwise allocate without bound before
+// their result is accounted for.
+func (rt *Runtime) reserve(fn *obj.Builtin, args []obj.Obj) *obj.Error {
+	estimate, ok := sizedBuiltins[fn]
+	if !ok || rt.limits.MaxAllocBytes <= 0 {
+		return nil
+	}
+
+	size := estimate(args)
+	if size > rt.limits.MaxAllocBytes-rt.alloc.Load() {
+		return newKindError(obj.ALLOC_LIMIT, "allocation limit exceeded")
+	}
+	return nil
+}
+
+// sizedBuiltins estimate the bytes a call to each builtin whose result
+// grows with its arguments would allocate. Estimates err on the high
+// side; arguments of the wrong type estimate nothing and are left for
+// the builtin to reject.
+var sizedBuiltins map[*obj.Builtin]func(args []obj.Obj) int64
+
+// initSizedBuiltins is called by the init in builtins.go.
+func initSizedBuiltins() {
+	sizedBuiltins = map[*obj.Builtin]func(args []obj.Obj) int64{
+		stringsBuiltins["repeat"]: func(args []obj.Obj) int64 {
+			if len(args) != 2 {
+				return 0
+			}
+			s, ok1 := args[0].(*obj.String)
+			n, ok2 := args[1].(*obj.Integer)
+			if !ok1 || !ok2 || n.Value <= 0 {
+				return 0
+			}
+			return mulSize(n.Value, int64(len(s.Value)))
+		},
+		stringsBuiltins["replace"]: func(args []obj.Obj) int64 {
+			if len(args) != 3 {
+				return 0
+			}
+			s, ok1 := args[0].(*obj.String)
+			old, ok2 := args[1].(*obj.String)
+			new, ok3 := args[2].(*obj.String)
+			if !ok1 || !ok2 || !ok3 || len(new.Value) <= len(old.Value) {
+				return 0
+			}
+			n := int64(strings.Count(s.Value, old.Value))
+			return addSize(int64(len(s.Value)), mulSize(n, int64(len(new.Value)-len(old.Value))))
+		},
+		stringsBuiltins["join"]: func(args []obj.Obj) int64 {
+			if len(args) != 2 {
+				return 0
+			}
+			a, ok1 := args[0].(*obj.Array)
+			sep, ok2 := args[1].(*obj.String)
+			if !ok1 || !ok2 || len(a.Elems) == 0 {
+				return 0
+			}
+			size := mulSize(int64(len(a.Elems)-1), int64(len(sep.Value)))
+			for _, e := range a.Elems {
+				if str, ok := e.(*obj.String); ok {
+					size = addSize(size, int64(len(str.Value)))
+				}
+			}
+			return size
+		},
+		stringsBuiltins["split"]: func(args []obj.Obj) int64 {
+			if len(args) != 2 {
+				return 0
+			}
+			s, ok1 := args[0].(*obj.String)
+			sep, ok2 := args[1].(*obj.String)
+			if !ok1 || !ok2 {
+				return 0
+			}
+			// Each part is a String of its own, held by a slot of the array.
+			n := int64(strings.Count(s.Value, sep.Value) + 1)
+			return sizeOf(&obj.Array{}) + n*4*8 + int64(len(s.Value))
+		},
+	}
+
+	// The list builders collect their arguments into a slice first; only
+	// that of a range is not already accounted for.
+	collecting := []*obj.Builtin{
+		listBuiltins["map"], listBuiltins["filter"], listBuiltins["reduce"],
+		listBuiltins["sort"], listBuiltins["zip"], listBuiltins["reverse"],
+		seqBuiltins["collect"],
+	}
+	for _, b := range collecting {
+		sizedBuiltins[b] = collectedSize
+	}
+}
+
+// runBuiltins are the builtins that go over the elements of their
+// arguments, calling back into scripts. Called from code running under a
+// Runtime, they count a step for every element, so that its limits and
+// cancellation stop them.
+var runBuiltins map[*obj.Builtin]func(rt *Runtime, args []obj.Obj) obj.Obj
+
+// initRunBuiltins is called by the init in builtins.go.
+func initRunBuiltins() {
+	runBuiltins = map[*obj.Builtin]func(rt *Runtime, args []obj.Obj) obj.Obj{
+		listBuiltins["map"]:     listMap,
+		listBuiltins["filter"]:  listFilter,
+		listBuiltins["reduce"]:  listReduce,
+		listBuiltins["sort"]:    listSort,
+		listBuiltins["zip"]:     listZip,
+		listBuiltins["reverse"]: listReverse,
+		seqBuiltins["collect"]:  seqCollect,
+	}
+}
+
+// collectedSize estimates the bytes taken by collecting the ranges among
+// args into arrays.
+func collectedSize(args []obj.Obj) int64 {
+	var size int64
+	for _, arg := range args {
+		if r, ok := arg.(*obj.Range); ok {
+			size += sizeOf(&obj.Array{}) + r.Len()*2*8
+		}
+	}
+	return size
+}
+
+// addSize and mulSize combine sizes, saturating at math.MaxInt64 rather
+// than overflowing.
+func addSize(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+func mulSize(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > math.MaxInt64/b {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+// safely returns the result of f, or an error if f panics: a bug in the
+// package or in a host builtin fails the evaluation, not the host.
+func safely(f func() obj.Obj) (result obj.Obj) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError("internal error: %v", r)
+		}
+	}()
+	return f()
+}
+
+// sizeOf approximates the bytes held by o, not counting its elements.
+func sizeOf(o obj.Obj) int64 {
+	const word = 8
+
+	switch o := o.(type) {
+	case *obj.String:
+		return int64(len(o.Value)) + 2*word
+	case *obj.Array:
+		return int64(len(o.Elems)+3) * 2 * word
+	case *obj.Hash:
+		return int64(len(o.Keys)) * 6 * word
+	case *obj.Struct:
+		return int64(len(o.Values)+4) * 2 * word
+	case *obj.Variant:
+		return int64(len(o.Values)+4) * 2 * word
+	default:
+		return 0
+	}
+}
+
+// allows reports whether the run permits fn, a builtin reached under
+// name. Builtins other than the ones of this package, such as those
+// registered by the host, are always allowed.
+func (rt *Runtime) allows(name string, fn obj.Obj) bool {
+	if rt == nil || rt.allowed == nil || rt.allowed[name] {
+		return true
+	}
+
+	known := false
+	for n, b := range builtins {
+		if b == fn {
+			known = true
+			if rt.allowed[n] {
+				return true
+			}
+		}
+	}
+	for mod, fns := range stdlibBuiltins {
+		for n, b := range fns {
+			if b == fn {
+				known = true
+				if rt.allowed[mod] || rt.allowed[mod+"."+n] {
+					return true
+				}
+			}
+		}
+	}
+
+	return !known
+}
+
+// allowsModule reports whether the run permits importing the stdlib
+// module called name, which it does if it allows any of its builtins.
+func (rt *Runtime) allowsModule(name string) bool {
+	if rt == nil || rt.allowed == nil || rt.allowed[name] {
+		return true
+	}
+
+	for allowed := range rt.allowed {
+		if strings.HasPrefix(allowed, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSelector fails if left.name reaches a builtin the run of env does
+// not allow.
+func checkSelector(env *obj.Env, left obj.Obj, name string) *obj.Error {
+	rt := runOf(env)
+	if rt == nil || rt.allowed == nil {
+		return nil
+	}
+
+	if m, ok := left.(*obj.Module); ok {
+		if val, ok := m.Env.Get(name); ok && m.Path == "" && !rt.allows(m.Name+"."+name, val) {
+			return newKindError(obj.FORBIDDEN, "builtin %s.%s is not allowed", m.Name, name)
+		}
+		return nil
+	}
+	if h, ok := left.(*obj.Hash); ok {
+		if _, ok := h.Get(&obj.String{Value: name}); ok {
+			return nil
+		}
+	}
+
+	if method := findMethod(rt, left, name); method != nil && !rt.allows(name, method) {
+		return newKindError(obj.FORBIDDEN, "method %s is not allowed", name)
+	}
+	return nil
+}
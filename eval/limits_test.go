@@ -0,0 +1,228 @@
+package eval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdaisuke/monk/lexer"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/parser"
+)
+
+func testEvalWithLimits(input string, limits Limits) obj.Obj {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := obj.NewEnv()
+
+	return EvalWithLimits(program, env, limits)
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		limits   Limits
+		expected interface{}
+	}{
+		{"fn f() { f() } f()", Limits{MaxSteps: 1000}, obj.STEP_LIMIT},
+		{`import "list"; for (x in list.range(1000000)) { x }`, Limits{MaxSteps: 1000}, obj.STEP_LIMIT},
+		{"fn sum(n) { if (n == 0) { 0 } else { n + sum(n - 1) } } sum(100)", Limits{MaxSteps: 1000}, 5050},
+		{"fn f() { f() } f()", Limits{MaxTime: 20 * time.Millisecond}, obj.TIME_LIMIT},
+		{"fn f(n) { 1 + f(n) } f(0)", Limits{MaxDepth: 50}, obj.DEPTH_LIMIT},
+		{"fn f(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } } f(49)", Limits{MaxDepth: 50}, 49},
+		{"fn f(n) { 1 + f(n) } f(0)", Limits{}, obj.DEPTH_LIMIT},
+		{`fn grow(s) { grow(s + s) } grow("ab")`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`import "list"; fn grow(n) { list.map(list.range(n), fn(x) { x }); grow(n * 2) } grow(1)`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`fn grow(s) { grow(s.repeat(2)) } grow("ab")`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`len("ab" + "cd")`, Limits{MaxAllocBytes: 1 << 20}, 4},
+		{`"ab".repeat(1000000000000)`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`import "strings"; strings.repeat("ab", 1000000000000)`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`"ab".repeat(3).len()`, Limits{MaxAllocBytes: 1 << 20}, 6},
+		{`import "strings"; let s = "a".repeat(100000); strings.replace(s, "", s)`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`let s = "ab".repeat(100000); s.replace("b", s)`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`import "strings"; strings.replace("ab".repeat(1000), "b", "cd").len()`, Limits{MaxAllocBytes: 1 << 20}, 3000},
+		{`import "list"; import "strings"; let s = "a".repeat(200000); strings.join(list.map(list.range(10000), fn(x) { s }), "")`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`import "strings"; strings.join(["ab", "cd"], ", ").len()`, Limits{MaxAllocBytes: 1 << 20}, 6},
+		{`import "strings"; strings.split("a".repeat(100000), "")`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`import "strings"; strings.split("a".repeat(100000), ",").len()`, Limits{MaxAllocBytes: 1 << 20}, 1},
+		{`import "list"; list.reverse(list.range(1000000000000))`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`import "list"; list.range(1000000000000).collect()`, Limits{MaxAllocBytes: 1 << 20}, obj.ALLOC_LIMIT},
+		{`import "list"; len(list.reverse(list.range(10)))`, Limits{MaxAllocBytes: 1 << 20}, 10},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithLimits(tt.input, tt.limits)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case obj.ErrorKind:
+			testErrorKind(t, tt.input, evaluated, expected)
+		}
+	}
+}
+
+func TestBuiltinAllowList(t *testing.T) {
+	tests := []struct {
+		input    string
+		builtins []string
+		expected interface{}
+	}{
+		{`len("abc")`, []string{"len"}, 3},
+		{`len("abc")`, []string{}, "builtin len is not allowed"},
+		{`"abc".len()`, []string{}, "method len is not allowed"},
+		{`"abc".len()`, []string{"len"}, 3},
+		{`import "strings"; len(strings.split("a,b", ","))`, []string{"len", "strings"}, 2},
+		{`import "strings"; len(strings.split("a,b", ","))`, []string{"len", "strings.split"}, 2},
+		{`import "strings"; strings.join(["a"], ",")`, []string{"strings.split"}, "builtin strings.join is not allowed"},
+		{`import "strings"`, []string{"len"}, "module strings is not allowed"},
+		{`len("a,b".split(","))`, []string{"len", "strings.split"}, 2},
+		{`"a,b".split(",")`, []string{"len"}, "method split is not allowed"},
+		{`let f = fn(x) { x * 2 }; f(21)`, []string{}, 42},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithLimits(tt.input, Limits{Builtins: tt.builtins})
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+			testErrorKind(t, tt.input, evaluated, obj.FORBIDDEN)
+		}
+	}
+}
+
+func TestEvalWithLimitsLeavesEnv(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn() { 1 + f() };")).ParseProgram()
+	env := obj.NewEnv()
+
+	EvalWithLimits(program, env, Limits{MaxSteps: 100})
+
+	if _, ok := env.Get(runKey); ok {
+		t.Fatalf("run still bound after EvalWithLimits")
+	}
+
+	call := parser.New(lexer.New("f()")).ParseProgram()
+	testErrorKind(t, "f()", Eval(call, env), obj.DEPTH_LIMIT)
+}
+
+func TestEvalContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		input    string
+		ctx      func() (context.Context, context.CancelFunc)
+		expected interface{}
+	}{
+		{"fn f() { f() } f()", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{`import "list"; for (x in list.range(1000000000)) { x }`, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{`import "list"; len(list.reverse(list.range(50000000)))`, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{`import "list"; len(list.map(list.range(50000000), fn(x) { x }))`, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{`import "list"; list.reduce(list.range(50000000), list.range, 0)`, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{`import "list"; len(list.range(50000000).collect())`, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{"fn f() { f() } f()", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+			return ctx, cancel
+		}, "evaluation canceled: context canceled"},
+		{"1 + 2", func() (context.Context, context.CancelFunc) {
+			return canceled, func() {}
+		}, "evaluation canceled: context canceled"},
+		{"fn spin() { spin() } spawn spin(); let c = chan(); recv(c)", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{"fn spin() { spin() } await(spawn spin())", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{"fn spin() { spin() } spawn spin(); let c = chan(); select { recv(c) => 1, send(c, 2) => 2 }", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{"fn spin() { spin() } spawn spin(); let c = chan(); let r = recv >> len; r(c)", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{`import "list"; fn spin() { spin() } spawn spin(); let c = chan(); list.filter([c], recv)`, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, "evaluation canceled: context deadline exceeded"},
+		{"fn sum(n) { if (n == 0) { 0 } else { n + sum(n - 1) } } sum(100)", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), time.Minute)
+		}, 5050},
+	}
+
+	for _, tt := range tests {
+		ctx, cancel := tt.ctx()
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := obj.NewEnv()
+
+		start := time.Now()
+		evaluated := EvalContext(ctx, program, env)
+		cancel()
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: took %s to give up", tt.input, elapsed)
+		}
+		if _, ok := env.Get(runKey); ok {
+			t.Errorf("%s: run still bound after EvalContext", tt.input)
+		}
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+			testErrorKind(t, tt.input, evaluated, obj.CANCELED)
+		}
+	}
+}
+
+func testErrorKind(t *testing.T, input string, o obj.Obj, expected obj.ErrorKind) {
+	t.Helper()
+
+	err, ok := o.(*obj.Error)
+	if !ok {
+		t.Errorf("%s: obj is not Error. got=%T(%+v)", input, o, o)
+		return
+	}
+	if err.Kind != expected {
+		t.Errorf("%s: wrong error kind. want=%q, got=%q (%s)", input, expected, err.Kind, err.Message)
+	}
+}
+
+func TestRuntimeRecoversPanics(t *testing.T) {
+	builtins := DefaultBuiltins()
+	builtins["boom"] = &obj.Builtin{Fn: func(args ...obj.Obj) obj.Obj {
+		panic("boom")
+	}}
+	rt := NewRuntime(builtins, nil, Limits{})
+
+	tests := []string{
+		"boom()",
+		"fn f() { boom() } f()",
+		"await(spawn boom())",
+		"fn g() { yield boom() } next(g())",
+	}
+
+	for _, input := range tests {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		evaluated := rt.Eval(context.Background(), program, obj.NewEnv())
+		testStringOrErrorObj(t, evaluated, "internal error: boom")
+	}
+
+	env := obj.NewEnv()
+	testStringOrErrorObj(t, rt.Apply(context.Background(), env, builtins["boom"]), "internal error: boom")
+}
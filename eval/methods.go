@@ -0,0 +1,285 @@
+package eval
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+// methods holds, per object type, the builtins that can be called on its
+// values with method syntax by code running outside of any Runtime:
+// recv.name(args) calls fn(recv, args...). Each Runtime has a table of its
+// own. Struct and enum types additionally carry the methods defined by
+// impl blocks.
+var methods map[obj.ObjType]map[string]*obj.Builtin
+
+// methodsMu guards methods against RegisterMethod.
+var methodsMu sync.RWMutex
+
+// defaultMethods is the table methods starts as, which the tables of
+// Runtimes are made from.
+var defaultMethods map[obj.ObjType]map[string]*obj.Builtin
+
+// initMethods is called by the init in builtins.go once builtins is set.
+func initMethods() {
+	defaultMethods = map[obj.ObjType]map[string]*obj.Builtin{
+		obj.STRING_OBJ: mergeBuiltins(stringsBuiltins, map[string]*obj.Builtin{
+			"len": builtins["len"],
+		}),
+		obj.ARRAY_OBJ: mergeBuiltins(listBuiltins, map[string]*obj.Builtin{
+			"len":  builtins["len"],
+			"join": stringsBuiltins["join"],
+		}),
+		obj.HASH_OBJ: mergeBuiltins(hashBuiltins, map[string]*obj.Builtin{
+			"len": builtins["len"],
+		}),
+		obj.RANGE_OBJ: mergeBuiltins(seqBuiltins, map[string]*obj.Builtin{
+			"len": builtins["len"],
+		}),
+		obj.ITERATOR_OBJ: mergeBuiltins(seqBuiltins, map[string]*obj.Builtin{
+			"next": builtins["next"],
+		}),
+	}
+
+	delete(defaultMethods[obj.ARRAY_OBJ], "range")
+
+	methods = make(map[obj.ObjType]map[string]*obj.Builtin)
+	for t, table := range defaultMethods {
+		methods[t] = mergeBuiltins(table)
+	}
+}
+
+// methodsFor returns the methods made of builtins: those whose builtin is
+// one of them, and those with no builtin of their own behind them, such
+// as the methods of hashes.
+func methodsFor(builtins map[string]*obj.Builtin) map[obj.ObjType]map[string]*obj.Builtin {
+	have := make(map[*obj.Builtin]bool)
+	for _, fn := range builtins {
+		have[fn] = true
+	}
+
+	table := make(map[obj.ObjType]map[string]*obj.Builtin)
+	for t, fns := range defaultMethods {
+		table[t] = make(map[string]*obj.Builtin)
+		for name, fn := range fns {
+			if have[fn] || !isPackageBuiltin(fn) {
+				table[t][name] = fn
+			}
+		}
+	}
+	return table
+}
+
+// isPackageBuiltin reports whether fn is one of the builtins of the
+// package, stdlib members included.
+func isPackageBuiltin(fn obj.Obj) bool {
+	for _, b := range builtins {
+		if b == fn {
+			return true
+		}
+	}
+	for _, fns := range stdlibBuiltins {
+		for _, b := range fns {
+			if b == fn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RegisterMethod makes fn callable as a method named name on every value
+// of type t in code running outside of any Runtime, replacing any
+// existing method of that name. Runtimes have methods of their own; see
+// Runtime.RegisterMethod.
+func RegisterMethod(t obj.ObjType, name string, fn *obj.Builtin) {
+	methodsMu.Lock()
+	defer methodsMu.Unlock()
+
+	registerMethod(methods, t, name, fn)
+}
+
+// RegisterMethod makes fn callable as a method named name on every value
+// of type t in the evaluations of rt, replacing any existing method of
+// that name. It must not be called during an evaluation.
+func (rt *Runtime) RegisterMethod(t obj.ObjType, name string, fn *obj.Builtin) {
+	registerMethod(rt.methods, t, name, fn)
+}
+
+func registerMethod(table map[obj.ObjType]map[string]*obj.Builtin, t obj.ObjType, name string, fn *obj.Builtin) {
+	if table[t] == nil {
+		table[t] = make(map[string]*obj.Builtin)
+	}
+	table[t][name] = fn
+}
+
+// MethodNames returns the sorted names of the methods callable on o by
+// code running outside of any Runtime.
+func MethodNames(o obj.Obj) []string {
+	names := []string{}
+	methodsMu.RLock()
+	for name := range methods[o.Type()] {
+		names = append(names, name)
+	}
+	methodsMu.RUnlock()
+	switch o := o.(type) {
+	case *obj.Struct:
+		for name := range o.StructType.Methods {
+			names = append(names, name)
+		}
+	case *obj.Variant:
+		for name := range o.VariantType.Enum.Methods {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func mergeBuiltins(tables ...map[string]*obj.Builtin) map[string]*obj.Builtin {
+	merged := make(map[string]*obj.Builtin)
+	for _, table := range tables {
+		for name, fn := range table {
+			merged[name] = fn
+		}
+	}
+	return merged
+}
+
+// findMethod returns the method called name on recv for code running
+// under rt, if not nil, or nil.
+func findMethod(rt *Runtime, recv obj.Obj, name string) obj.Obj {
+	switch recv := recv.(type) {
+	case *obj.Struct:
+		if method, ok := recv.StructType.Methods[name]; ok {
+			return method
+		}
+	case *obj.Variant:
+		if method, ok := recv.VariantType.Enum.Methods[name]; ok {
+			return method
+		}
+	}
+
+	if rt != nil {
+		if fn, ok := rt.methods[recv.Type()][name]; ok {
+			return fn
+		}
+		return nil
+	}
+
+	methodsMu.RLock()
+	defer methodsMu.RUnlock()
+	if fn, ok := methods[recv.Type()][name]; ok {
+		return fn
+	}
+	return nil
+}
+
+// lookupMethod returns the method called name on recv, bound to recv for
+// code running in env.
+func lookupMethod(env *obj.Env, recv obj.Obj, name string) (obj.Obj, bool) {
+	rt := runOf(env)
+	method := findMethod(rt, recv, name)
+	if method == nil {
+		return nil, false
+	}
+
+	return &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			return applyIn(rt, method, append([]obj.Obj{recv}, args...))
+		},
+	}, true
+}
+
+func evalImplStmt(is *ast.ImplStmt, env *obj.Env) obj.Obj {
+	target, ok := env.Get(is.Name.Value)
+	if !ok {
+		return newError("identifier not found: %s", is.Name.Value)
+	}
+	// Method tables are read by every evaluation sharing the type.
+	if scope := env.Resolve(is.Name.Value); scope.Frozen() {
+		return newError("cannot impl %s: it is bound in a frozen environment", is.Name.Value)
+	}
+
+	var table map[string]obj.Obj
+	switch target := target.(type) {
+	case *obj.StructType:
+		table = target.Methods
+	case *obj.EnumType:
+		table = target.Methods
+	default:
+		return newError("impl target must be STRUCT_TYPE or ENUM, got %s", target.Type())
+	}
+
+	implEnv := obj.NewEnclosedEnv(env)
+	if err := hoistFunctions(is.Body.Stmts, implEnv); err != nil {
+		return err
+	}
+
+	for _, stmt := range is.Body.Stmts {
+		var name string
+
+		switch stmt := stmt.(type) {
+		case *ast.LetStmt:
+			if val := Eval(stmt, implEnv); isError(val) {
+				return val
+			}
+			name = stmt.Name.Value
+		case *ast.FunctionStmt:
+			name = stmt.Name.Value
+		}
+
+		method, _ := implEnv.Get(name)
+		table[name] = method
+	}
+
+	return nil
+}
+
+var hashBuiltins = map[string]*obj.Builtin{
+	"keys": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("keys", args, obj.HASH_OBJ); err != nil {
+				return err
+			}
+
+			hash := args[0].(*obj.Hash)
+			keys := make([]obj.Obj, len(hash.Keys))
+			for i, hk := range hash.Keys {
+				keys[i] = hash.Pairs[hk].Key
+			}
+			return &obj.Array{Elems: keys}
+		},
+	},
+	"values": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("values", args, obj.HASH_OBJ); err != nil {
+				return err
+			}
+
+			hash := args[0].(*obj.Hash)
+			values := make([]obj.Obj, len(hash.Keys))
+			for i, hk := range hash.Keys {
+				values[i] = hash.Pairs[hk].Value
+			}
+			return &obj.Array{Elems: values}
+		},
+	},
+	"has": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("has", args, obj.HASH_OBJ, ANY); err != nil {
+				return err
+			}
+
+			key, ok := obj.AsHashable(args[1])
+			if !ok {
+				return newError("unusable as hash key: %s", args[1].Type())
+			}
+
+			_, ok = args[0].(*obj.Hash).Get(key)
+			return nativeBoolToBooleanObj(ok)
+		},
+	},
+}
@@ -0,0 +1,124 @@
+package eval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mdaisuke/monk/lexer"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/parser"
+)
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"  hi ".trim().upper()`, "HI"},
+		{`"a,b".split(",").join("-")`, "a-b"},
+		{`"abc".len()`, 3},
+		{`let up = "abc".upper; up()`, "ABC"},
+		{`[3, 1, 2].sort().reverse().join`, "builtin function"},
+		{`[1, 2, 3].map(fn(x) { x * 2 }).reduce(fn(a, b) { a + b }, 0)`, 12},
+		{`{"a": 1, "b": 2}.keys().join("")`, "ab"},
+		{`{"a": 1, "b": 2}.values().len()`, 2},
+		{`{"a": 1}.has("a")`, true},
+		{`{"a": 1}.has("b")`, false},
+		{`import "list"; list.range(10).filter(fn(x) { x > 5 }).take(2).collect().len()`, 2},
+		{`iter([1, 2]).skip(1).next()`, 2},
+		{`struct P { x, y }
+		  impl P {
+		    let sum = fn(self) { self.x + self.y };
+		    let scale = fn(self, n) { self with {x: self.x * n, y: self.y * n} };
+		  }
+		  P(1, 2).scale(3).sum()`, 9},
+		{`struct P { f }; P(fn(x) { x + 1 }).f(1)`, 2},
+		{`struct P { len }; impl P { let len = fn(self) { 99 }; }; P(5).len`, 5},
+		{`"abc".nope()`, "no method nope on STRING"},
+		{`[1].upper()`, "no method upper on ARRAY"},
+		{`struct P { x }; P(1).nope()`, "no field nope on struct P"},
+		{`"abc".repeat("x")`, "argument to `repeat` must be INTEGER, got STRING"},
+		{`let x = 1; impl x { }`, "impl target must be STRUCT_TYPE or ENUM, got INTEGER"},
+		{`impl Nope { }`, "identifier not found: Nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			if evaluated.Type() == obj.BUILTIN_OBJ {
+				if evaluated.Inspect() != expected {
+					t.Errorf("wrong inspect. expected=%q, got=%q",
+						expected, evaluated.Inspect())
+				}
+				continue
+			}
+			testStringOrErrorObj(t, evaluated, expected)
+		}
+	}
+}
+
+func TestRegisterMethod(t *testing.T) {
+	double := &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			return &obj.Integer{Value: args[0].(*obj.Integer).Value * 2}
+		},
+	}
+	RegisterMethod(obj.INTEGER_OBJ, "double", double)
+	defer delete(methods, obj.INTEGER_OBJ)
+
+	testIntegerObj(t, testEval(`let x = 21; x.double()`), 42)
+
+	names := MethodNames(&obj.Integer{Value: 1})
+	if len(names) != 1 || names[0] != "double" {
+		t.Errorf("wrong method names. got=%q", names)
+	}
+
+	// Runtimes have methods of their own.
+	testStringOrErrorObj(t, testEvalWithLimits(`let x = 21; x.double()`, Limits{}), "no method double on INTEGER")
+
+	rt := NewRuntime(nil, nil, Limits{})
+	rt.RegisterMethod(obj.INTEGER_OBJ, "triple", &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			return &obj.Integer{Value: args[0].(*obj.Integer).Value * 3}
+		},
+	})
+	program := parser.New(lexer.New(`let x = 2; x.triple()`)).ParseProgram()
+	testIntegerObj(t, rt.Eval(context.Background(), program, obj.NewEnv()), 6)
+	testStringOrErrorObj(t, testEval(`let x = 2; x.triple()`), "no method triple on INTEGER")
+}
+
+func TestRuntimeMethodsAndStdlibFollowBuiltins(t *testing.T) {
+	defaults := DefaultBuiltins()
+	tests := []struct {
+		builtins map[string]*obj.Builtin
+		input    string
+		expected interface{}
+	}{
+		{nil, `"ab".repeat(2).len()`, 4},
+		{map[string]*obj.Builtin{}, `"ab".repeat(2)`, "no method repeat on STRING"},
+		{map[string]*obj.Builtin{}, `[1].len()`, "no method len on ARRAY"},
+		{map[string]*obj.Builtin{}, `import "list"`, "module not found: list"},
+		{map[string]*obj.Builtin{}, `{"a": 1}.keys()[0]`, "a"},
+		{map[string]*obj.Builtin{"list.map": defaults["list.map"]}, `import "list"; list.map([1], fn(x) { x + 1 })[0]`, 2},
+		{map[string]*obj.Builtin{"list.map": defaults["list.map"]}, `[1].map(fn(x) { x + 1 })[0]`, 2},
+		{map[string]*obj.Builtin{"list.map": defaults["list.map"]}, `[1].filter(fn(x) { true })`, "no method filter on ARRAY"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := NewRuntime(tt.builtins, nil, Limits{}).Eval(context.Background(), program, obj.NewEnv())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		}
+	}
+}
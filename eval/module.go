@@ -0,0 +1,268 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/lexer"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/parser"
+)
+
+// Modules is the loader used by import statements. Its search path is
+// taken from the MONKPATH environment variable.
+var Modules = NewLoader(filepath.SplitList(os.Getenv("MONKPATH"))...)
+
+// Loader resolves import paths to files, evaluates each file once and
+// caches the resulting module. It is safe for concurrent use: a module
+// imported by several goroutines at once is loaded by one of them while
+// the others wait.
+type Loader struct {
+	SearchPath []string
+
+	mu      sync.Mutex
+	modules map[string]*load
+	runtime *Runtime // the Runtime modules are evaluated under, if any
+}
+
+// load is a module being loaded, or loaded once done is closed.
+type load struct {
+	loader *Loader
+	path   string
+	done   chan struct{}
+	result obj.Obj
+
+	// waiting is the load this one waits for, to import it, guarded by
+	// the Loader's mu.
+	waiting *load
+}
+
+func (ld *load) Type() obj.ObjType { return "LOAD" }
+func (ld *load) Inspect() string   { return "load " + ld.path }
+
+func (ld *load) finished() bool {
+	select {
+	case <-ld.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// moduleKey binds the load of a module in its Env, so that the imports it
+// runs go through the same Loader and resolve relative to it.
+const moduleKey = "<module>"
+
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{
+		SearchPath: searchPath,
+		modules:    make(map[string]*load),
+	}
+}
+
+// Import returns the standard library module called name if there is one.
+// Otherwise name is resolved relative to the working directory and then
+// against SearchPath.
+func (l *Loader) Import(name string) obj.Obj {
+	return l.run(func() obj.Obj { return l.importFrom(nil, name) })
+}
+
+// stdlib returns the stdlib modules of the Runtime of l, or those of the
+// package if none.
+func (l *Loader) stdlib() map[string]*obj.Module {
+	if l.runtime != nil {
+		return l.runtime.stdlib
+	}
+	return stdlib
+}
+
+// importFrom is Import run by the module loaded by from, if not nil. Then
+// name is resolved relative to its file first, and only there for names
+// starting with "./" or "../".
+func (l *Loader) importFrom(from *load, name string) obj.Obj {
+	if m, ok := l.stdlib()[name]; ok {
+		return m
+	}
+
+	path, ok := l.resolve(from, name)
+	if !ok {
+		return newError("module not found: %s", name)
+	}
+	return l.load(from, path)
+}
+
+// Load evaluates the file at path as a module. Only bindings declared with
+// export end up in the module's Env.
+func (l *Loader) Load(path string) obj.Obj {
+	return l.run(func() obj.Obj { return l.load(nil, path) })
+}
+
+// run calls f, an Import or Load by the host, as an evaluation of the
+// Runtime of l, if any: the modules it loads are held to its limits
+// whether or not one of its evaluations is under way.
+func (l *Loader) run(f func() obj.Obj) obj.Obj {
+	rt := l.runtime
+	if rt == nil {
+		return f()
+	}
+
+	if err := rt.enter(context.Background(), obj.NewEnv()); err != nil {
+		return err
+	}
+	defer rt.exit()
+
+	return safely(f)
+}
+
+func (l *Loader) load(from *load, path string) obj.Obj {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return newError("could not load module %s: %s", path, err)
+	}
+
+	l.mu.Lock()
+	ld, loading := l.modules[path]
+	if loading {
+		if cycle := ld.cycle(from); cycle != nil {
+			l.mu.Unlock()
+			return newError("import cycle: %s", formatCycle(cycle))
+		}
+	} else {
+		ld = &load{loader: l, path: path, done: make(chan struct{})}
+		l.modules[path] = ld
+	}
+	if from != nil && !from.finished() {
+		from.waiting = ld
+	}
+	l.mu.Unlock()
+
+	if !loading {
+		ld.result = l.eval(ld)
+
+		l.mu.Lock()
+		if isError(ld.result) {
+			delete(l.modules, path)
+		}
+		l.mu.Unlock()
+		close(ld.done)
+	}
+	<-ld.done
+
+	if from != nil {
+		l.mu.Lock()
+		from.waiting = nil
+		l.mu.Unlock()
+	}
+
+	return ld.result
+}
+
+// cycle returns the paths of the import cycle from waiting for ld would
+// close, or nil if it would close none.
+func (ld *load) cycle(from *load) []string {
+	if from == nil {
+		return nil
+	}
+
+	var paths []string
+	for w := ld; w != nil; w = w.waiting {
+		paths = append(paths, w.path)
+		if w == from {
+			return append(paths, ld.path)
+		}
+	}
+	return nil
+}
+
+func (l *Loader) eval(ld *load) obj.Obj {
+	src, err := os.ReadFile(ld.path)
+	if err != nil {
+		return newError("could not load module %s: %s", displayPath(ld.path), err)
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("could not parse module %s: %s",
+			displayPath(ld.path), strings.Join(p.Errors(), "; "))
+	}
+
+	env := obj.NewEnv()
+	env.Set(moduleKey, ld)
+	if l.runtime != nil {
+		env.Set(runKey, l.runtime)
+	}
+	if result := Eval(program, env); isError(result) {
+		return result
+	}
+
+	exports := obj.NewEnv()
+	for _, stmt := range program.Stmts {
+		if es, ok := stmt.(*ast.ExportStmt); ok {
+			if val, ok := env.Get(es.Name.Value); ok {
+				exports.Set(es.Name.Value, val)
+			}
+		}
+	}
+
+	return &obj.Module{Name: moduleName(ld.path), Path: ld.path, Env: exports}
+}
+
+func (l *Loader) resolve(from *load, name string) (string, bool) {
+	if filepath.IsAbs(name) {
+		return name, isFile(name)
+	}
+
+	dir := "."
+	if from != nil {
+		dir = filepath.Dir(from.path)
+	}
+
+	dirs := []string{dir}
+	if !strings.HasPrefix(name, "./") && !strings.HasPrefix(name, "../") {
+		dirs = append(dirs, l.SearchPath...)
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if isFile(path) {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func moduleName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+func formatCycle(paths []string) string {
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = displayPath(p)
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,188 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func testLoad(t *testing.T, loader *Loader, path string) obj.Obj {
+	t.Helper()
+
+	saved := Modules
+	Modules = loader
+	defer func() { Modules = saved }()
+
+	return Modules.Load(path)
+}
+
+func testExport(t *testing.T, o obj.Obj, name string) obj.Obj {
+	t.Helper()
+
+	m, ok := o.(*obj.Module)
+	if !ok {
+		t.Fatalf("obj is not Module. got=%T(%+v)", o, o)
+	}
+
+	val, ok := m.Env.Get(name)
+	if !ok {
+		t.Fatalf("module %s does not export %s", m.Name, name)
+	}
+
+	return val
+}
+
+func TestImportRelativeToImporter(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `
+		import "lib/math.mk" as m;
+		export let result = m.double(m.base);
+		`,
+		"lib/math.mk": `
+		import "./consts.mk";
+		let twice = fn(x) { x * 2 };
+		export let double = fn(x) { twice(x) };
+		export let base = consts.base;
+		`,
+		"lib/consts.mk": `export let base = 21;`,
+	})
+
+	result := testLoad(t, NewLoader(), filepath.Join(dir, "main.mk"))
+	testIntegerObj(t, testExport(t, result, "result"), 42)
+}
+
+func TestMatchImportedEnum(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `
+		import "shapes.mk" as s;
+		let area = fn(shape) {
+			match (shape) {
+				s.Shape.Circle(r) => 3 * r * r,
+				s.Shape.Rect(w, h) => w * h,
+				s.Shape.Empty => 0
+			}
+		};
+		export let result = area(s.Shape.Rect(2, 3)) + area(s.Shape.Circle(1)) + area(s.Shape.Empty);
+		`,
+		"shapes.mk": `export enum Shape { Circle(r), Rect(w, h), Empty };`,
+	})
+
+	result := testLoad(t, NewLoader(), filepath.Join(dir, "main.mk"))
+	testIntegerObj(t, testExport(t, result, "result"), 9)
+}
+
+func TestImportSearchPath(t *testing.T) {
+	lib := writeModules(t, map[string]string{
+		"greet.mk": `export let greeting = "hello";`,
+	})
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "greet.mk" as g; export let result = g.greeting;`,
+	})
+
+	result := testLoad(t, NewLoader(lib), filepath.Join(dir, "main.mk"))
+	str, ok := testExport(t, result, "result").(*obj.String)
+	if !ok || str.Value != "hello" {
+		t.Errorf("result is not \"hello\". got=%+v", str)
+	}
+}
+
+func TestImportCachesModules(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `
+		import "a.mk";
+		import "b.mk";
+		export let same = a.shared == b.shared;
+		`,
+		"a.mk":      `import "shared.mk"; export let shared = shared;`,
+		"b.mk":      `import "shared.mk"; export let shared = shared;`,
+		"shared.mk": `export let value = 1;`,
+	})
+
+	result := testLoad(t, NewLoader(), filepath.Join(dir, "main.mk"))
+	testBooleanObj(t, testExport(t, result, "same"), true)
+}
+
+func TestRuntimeLoaderOutsideEvaluations(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.mk": `import "list"; export let total = list.reduce([1, 2, 3], fn(a, b) { a + b }, 0);`,
+		"spin.mk": `fn spin() { spin() } spin();`,
+	})
+
+	loader := NewLoader(dir)
+	rt := NewRuntime(nil, loader, Limits{MaxSteps: 1000})
+
+	testIntegerObj(t, testExport(t, loader.Load(filepath.Join(dir, "main.mk")), "total"), 6)
+	testIntegerObj(t, testExport(t, loader.Import("main.mk"), "total"), 6)
+	testErrorKind(t, "spin.mk", loader.Import("spin.mk"), obj.STEP_LIMIT)
+
+	// After an evaluation of rt is over too.
+	rt.Eval(context.Background(), &ast.Program{}, obj.NewEnv())
+	testErrorKind(t, "spin.mk", loader.Import("spin.mk"), obj.STEP_LIMIT)
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"cycle.mk":   `import "a.mk";`,
+		"a.mk":       `import "b.mk";`,
+		"b.mk":       `import "a.mk";`,
+		"missing.mk": `import "nope.mk";`,
+		"private.mk": `import "lib.mk"; lib.hidden;`,
+		"lib.mk":     `let hidden = 1; export let shown = 2;`,
+		"broken.mk":  `let x 1;`,
+	})
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		file            string
+		expectedMessage string
+	}{
+		{"cycle.mk", "import cycle: a.mk -> b.mk -> a.mk"},
+		{"missing.mk", "module not found: nope.mk"},
+		{"private.mk", "module lib has no export hidden"},
+		{"broken.mk", "could not parse module broken.mk: expected next token to be =, got=INT instead"},
+	}
+
+	for _, tt := range tests {
+		result := testLoad(t, NewLoader(), filepath.Join(dir, tt.file))
+
+		errObj, ok := result.(*obj.Error)
+		if !ok {
+			t.Errorf("no error obj for %s. got=%T(%+v)", tt.file, result, result)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package eval
+
+import (
+	"testing"
+)
+
+func TestNullAndOptionalChaining(t *testing.T) {
+	config := `let cfg = {"db": {"port": 5432}, "tags": ["a"]}; `
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null", nil},
+		{"null == null", true},
+		{"let x = null; x != null", false},
+		{"[1][5] == null", true},
+		{"null ?? 1", 1},
+		{"2 ?? 1", 2},
+		{"false ?? 1", false},
+		{"null ?? null ?? 3", 3},
+		{"1 ?? nope", 1},
+		{"null ?? nope", "identifier not found: nope"},
+		{config + `cfg?["db"]?["port"]`, 5432},
+		{config + `cfg?["cache"]?["port"]`, nil},
+		{config + `cfg?["cache"]?["port"] ?? 6379`, 6379},
+		{config + `cfg["tags"]?[0]`, "a"},
+		{`let a = null; a?[nope]`, nil},
+		{"struct P { x }; let p = P(1); p?.x", 1},
+		{"struct P { x }; let p = null; p?.x", nil},
+		{"struct P { x }; let p = null; p?.x?.y ?? 0", 0},
+		{"struct P { x }; let q = P(null); q.x?.y", nil},
+		{`"ab"?.len()`, 2},
+		{`"ab"?.upper()`, "AB"},
+		{"let s = null; s?.upper()", nil},
+		{"let s = null; s?.len() ?? 0", 0},
+		{"let s = null; s?.trim().len()", nil},
+		{"let s = null; s?.x.y", nil},
+		{"let s = null; s?.upper()[0]", nil},
+		{config + `cfg?["cache"]?["hosts"].len() ?? 0`, 0},
+		{"let s = null; fn f() { s?.upper() }; f()", nil},
+		{"let s = null; s?.upper(nope)", nil},
+		{`let s = "ab"; s?.upper(nope)`, "identifier not found: nope"},
+		{"let p = null; p.x", "no method x on NULL"},
+		{"let p = null; p[0]", "index op not supported: NULL"},
+		{"struct P { x }; P(1)?.y", "no field y on struct P"},
+		{`let c = {"db": {"host": "h"}}; c?.db?.host`, "h"},
+		{`let c = {"db": {"host": "h"}}; c.db.host`, "h"},
+		{`let c = {"db": {"host": "h"}}; c?.cache?.host`, nil},
+		{`let c = {"db": {"host": "h"}}; c.db.port`, nil},
+		{`let c = {"db": {"host": "h"}}; c.cache?.host ?? "localhost"`, "localhost"},
+		{`let c = null; c?.db?.host`, nil},
+		{config + `cfg.db.port`, 5432},
+		{config + `cfg.tags.len()`, 1},
+		{`{"a": 1}.keys()[0]`, "a"},
+		{`{"keys": 1}.keys`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case nil:
+			testNullObj(t, evaluated)
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		}
+	}
+}
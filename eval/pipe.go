@@ -0,0 +1,55 @@
+package eval
+
+import (
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+// evalPipeExp evaluates `left |> f(args)` as f(left, args) and
+// `left |> f` as f(left).
+func evalPipeExp(pe *ast.PipeExp, env *obj.Env) obj.Obj {
+	left := Eval(pe.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	call, ok := pe.Right.(*ast.CallExp)
+	if !ok {
+		function := Eval(pe.Right, env)
+		if isError(function) {
+			return function
+		}
+		return applyFrom(env, function, []obj.Obj{left})
+	}
+
+	function := Eval(call.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args := evalExps(call.Args, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return applyFrom(env, function, append([]obj.Obj{left}, args...))
+}
+
+func evalComposeExp(first, then obj.Obj) obj.Obj {
+	if !isCallable(first) || !isCallable(then) {
+		return newError("unknown op: %s >> %s", first.Type(), then.Type())
+	}
+
+	return &obj.Composed{First: first, Then: then}
+}
+
+// isCallable reports whether applyFunction accepts fn.
+func isCallable(fn obj.Obj) bool {
+	switch fn.(type) {
+	case *obj.Function, *obj.Builtin, *obj.Composed,
+		*obj.StructType, *obj.VariantType:
+		return true
+	default:
+		return false
+	}
+}
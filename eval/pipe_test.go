@@ -0,0 +1,53 @@
+package eval
+
+import (
+	"testing"
+)
+
+func TestPipeAndCompose(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let inc = fn(x) { x + 1 }; 1 |> inc", 2},
+		{"let add = fn(a, b) { a + b }; 1 |> add(2)", 3},
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(3)", 7},
+		{"let inc = fn(x) { x + 1 }; let dbl = fn(x) { x * 2 }; 1 |> inc |> dbl", 4},
+		{"1 + 2 |> fn(x) { x * 10 }", 30},
+		{"[1, 2] |> len == 2", true},
+		{`import "list"; [1, 2, 3, 4] |> list.filter(fn(x) { x > 1 }) |> list.map(fn(x) { x * x })`, "[4, 9, 16]"},
+		{`import "strings"; "a,b" |> strings.split(",") |> len`, 2},
+		{`"a b" |> "x".replace(" ", "-")`, "wrong number of arguments. got=4, want=3"},
+		{"let inc = fn(x) { x + 1 }; let dbl = fn(x) { x * 2 }; (inc >> dbl)(1)", 4},
+		{"let inc = fn(x) { x + 1 }; let dbl = fn(x) { x * 2 }; (dbl >> inc)(1)", 3},
+		{"let add = fn(a, b) { a + b }; let dbl = fn(x) { x * 2 }; (add >> dbl)(1, 2)", 6},
+		{"let inc = fn(x) { x + 1 }; let f = inc >> inc >> inc; f(0)", 3},
+		{"let inc = fn(x) { x + 1 }; let dbl = fn(x) { x * 2 }; 1 |> inc >> dbl", 4},
+		{"struct Box { v }; let inc = fn(x) { x + 1 }; (inc >> Box)(1)", "Box{v: 2}"},
+		{`import "list"; let inc = fn(x) { x + 1 }; list.map([1, 2], inc >> inc)`, "[3, 4]"},
+		{"len >> len", "(builtin function >> builtin function)"},
+		{"1 |> 2", "not a function: INTEGER"},
+		{"let inc = fn(x) { x + 1 }; inc >> 1", "unknown op: FUNCTION >> INTEGER"},
+		{"1 >> 2", "unknown op: INTEGER >> INTEGER"},
+		{"let f = fn(x) { x + true } >> len; f(1)", "type mismatch: INTEGER + BOOLEAN"},
+		{"1 |> nope(2)", "identifier not found: nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			if isError(evaluated) {
+				testStringOrErrorObj(t, evaluated, expected)
+			} else if evaluated.Inspect() != expected {
+				t.Errorf("wrong inspect. expected=%q, got=%q",
+					expected, evaluated.Inspect())
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package eval
+
+import (
+	"sync/atomic"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+// MaxDepth bounds the number of nested function calls. A call beyond it
+// fails with "maximum recursion depth exceeded" instead of exhausting
+// memory. Calls in tail position do not nest.
+var MaxDepth = 10000
+
+// hopDepth is how many nested calls run on one goroutine. Every hopDepth
+// calls, evaluation continues on a fresh goroutine while the caller's
+// waits, so deep recursion is spread over many small stacks, all of them
+// on the heap, instead of reaching the size limit of a single one.
+const hopDepth = 1000
+
+// threadKey binds, in the Env of each call, the thread making it.
+const threadKey = "<thread>"
+
+// thread counts the calls in progress on one line of execution: an
+// evaluation started by the host, a task or the body of a generator.
+// Threads running at once count their calls apart.
+type thread struct {
+	depth atomic.Int64
+}
+
+func (th *thread) Type() obj.ObjType { return "THREAD" }
+func (th *thread) Inspect() string   { return "thread" }
+
+// mainThread makes the calls of code running outside of any Runtime, but
+// for those of the tasks and generators it starts.
+var mainThread = &thread{}
+
+// forkThread returns a thread started by a call at the depth of parent, so
+// that recursion through tasks and generators stays bounded.
+func forkThread(parent *thread) *thread {
+	th := &thread{}
+	th.depth.Store(parent.depth.Load())
+	return th
+}
+
+// threadOf returns the thread of code running in env under rt, if not
+// nil: the one making the innermost call in progress, or else the one
+// that started the evaluation.
+func threadOf(env *obj.Env, rt *Runtime) *thread {
+	if o, ok := env.Get(threadKey); ok {
+		return o.(*thread)
+	}
+	if rt != nil {
+		return rt.thread
+	}
+	return mainThread
+}
+
+// applyUserFunction calls fn on th under rt, if not nil.
+func applyUserFunction(fn *obj.Function, args []obj.Obj, rt *Runtime, th *thread) obj.Obj {
+	max := MaxDepth
+	if rt != nil && rt.limits.MaxDepth > 0 {
+		max = rt.limits.MaxDepth
+	}
+
+	d := th.depth.Add(1)
+	defer th.depth.Add(-1)
+
+	if d > int64(max) {
+		return newKindError(obj.DEPTH_LIMIT, "maximum recursion depth exceeded")
+	}
+
+	if d%hopDepth == 0 {
+		result := make(chan obj.Obj)
+		go func() {
+			result <- safely(func() obj.Obj { return callUserFunction(fn, args, rt, th) })
+		}()
+		return <-result
+	}
+
+	return callUserFunction(fn, args, rt, th)
+}
@@ -0,0 +1,62 @@
+package eval
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestDeepRecursion(t *testing.T) {
+	// Far too small for the deepest of these to fit on one stack.
+	defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+	saved := MaxDepth
+	MaxDepth = 30000
+	defer func() { MaxDepth = saved }()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn count(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } } count(20000)", 20000},
+		{`
+		fn nest(n) { if (n == 0) { [] } else { [nest(n - 1)] } }
+		fn depthOf(xs) { if (len(xs) == 0) { 0 } else { 1 + depthOf(xs[0]) } }
+		depthOf(nest(10000))
+		`, 10000},
+		{`
+		import "list";
+		fn sum(xs) { list.reduce(xs, fn(acc, x) { acc + x }, 0) }
+		fn tree(n) { if (n == 0) { 1 } else { sum(list.map([n], fn(m) { tree(m - 1) })) } }
+		tree(10000)
+		`, 1},
+		{"fn forever(n) { 1 + forever(n + 1) } forever(0)", "maximum recursion depth exceeded"},
+		{"fn count(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } } count(30000)", "maximum recursion depth exceeded"},
+		{"fn count(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } } count(29999)", 29999},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		}
+
+		if d := mainThread.depth.Load(); d != 0 {
+			t.Fatalf("depth is not 0 after evaluation. got=%d", d)
+		}
+	}
+}
+
+func TestMaxDepthIsConfigurable(t *testing.T) {
+	saved := MaxDepth
+	MaxDepth = 10
+	defer func() { MaxDepth = saved }()
+
+	count := "fn count(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } } "
+
+	testIntegerObj(t, testEval(count+"count(9)"), 9)
+	testStringOrErrorObj(t, testEval(count+"count(10)"), "maximum recursion depth exceeded")
+}
@@ -0,0 +1,87 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+// ANY matches every argument type in checkArgs.
+const ANY obj.ObjType = "ANY"
+
+// stdlib holds the native modules that import resolves by bare name
+// before looking at the file system, e.g. `import "strings" as s`.
+var stdlib map[string]*obj.Module
+
+// stdlibBuiltins holds the builtins each stdlib module is made of.
+var stdlibBuiltins map[string]map[string]*obj.Builtin
+
+func init() {
+	stdlibBuiltins = map[string]map[string]*obj.Builtin{
+		"strings": stringsBuiltins,
+		"math":    mathBuiltins,
+		"list":    listBuiltins,
+		"seq":     seqBuiltins,
+	}
+
+	stdlib = make(map[string]*obj.Module)
+	for name, fns := range stdlibBuiltins {
+		stdlib[name] = nativeModule(name, fns)
+	}
+}
+
+// stdlibFor returns the stdlib modules made of builtins: each holds the
+// builtins named after it, like "strings.split".
+func stdlibFor(builtins map[string]*obj.Builtin) map[string]*obj.Module {
+	members := make(map[string]map[string]*obj.Builtin)
+	for name, fn := range builtins {
+		mod, member, ok := strings.Cut(name, ".")
+		if !ok {
+			continue
+		}
+		if members[mod] == nil {
+			members[mod] = make(map[string]*obj.Builtin)
+		}
+		members[mod][member] = fn
+	}
+
+	modules := make(map[string]*obj.Module)
+	for mod, fns := range members {
+		modules[mod] = nativeModule(mod, fns)
+	}
+	return modules
+}
+
+func nativeModule(name string, fns map[string]*obj.Builtin) *obj.Module {
+	env := obj.NewEnv()
+	for n, fn := range fns {
+		env.Set(n, fn)
+	}
+	return &obj.Module{Name: name, Env: env}
+}
+
+// checkArgs validates the number and types of arguments passed to the
+// builtin called name.
+func checkArgs(name string, args []obj.Obj, want ...obj.ObjType) *obj.Error {
+	if len(args) != len(want) {
+		return newError("wrong number of arguments. got=%d, want=%d",
+			len(args), len(want))
+	}
+
+	for i, t := range want {
+		if t != ANY && args[i].Type() != t {
+			return newError("argument to `%s` must be %s, got %s",
+				name, t, args[i].Type())
+		}
+	}
+
+	return nil
+}
+
+func checkCallable(name string, arg obj.Obj) *obj.Error {
+	if !isCallable(arg) {
+		return newError("argument to `%s` must be FUNCTION, got %s",
+			name, arg.Type())
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package eval
+
+import (
+	"sort"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+var listBuiltins = map[string]*obj.Builtin{
+	"map": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj { return listMap(nil, args) },
+	},
+	"filter": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj { return listFilter(nil, args) },
+	},
+	"reduce": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj { return listReduce(nil, args) },
+	},
+	"sort": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj { return listSort(nil, args) },
+	},
+	"zip": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj { return listZip(nil, args) },
+	},
+	"range": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			var start, end, step int64 = 0, 0, 1
+
+			switch len(args) {
+			case 1:
+				if err := checkArgs("range", args, obj.INTEGER_OBJ); err != nil {
+					return err
+				}
+				end = args[0].(*obj.Integer).Value
+			case 2:
+				if err := checkArgs("range", args, obj.INTEGER_OBJ, obj.INTEGER_OBJ); err != nil {
+					return err
+				}
+				start = args[0].(*obj.Integer).Value
+				end = args[1].(*obj.Integer).Value
+			default:
+				if err := checkArgs("range", args, obj.INTEGER_OBJ, obj.INTEGER_OBJ, obj.INTEGER_OBJ); err != nil {
+					return err
+				}
+				start = args[0].(*obj.Integer).Value
+				end = args[1].(*obj.Integer).Value
+				step = args[2].(*obj.Integer).Value
+			}
+
+			if step == 0 {
+				return newError("argument to `range` must not be zero, got step 0")
+			}
+
+			return &obj.Range{Start: start, End: end, Step: step}
+		},
+	},
+	"reverse": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj { return listReverse(nil, args) },
+	},
+}
+
+func listMap(rt *Runtime, args []obj.Obj) obj.Obj {
+	if err := checkArgs("map", args, ANY, ANY); err != nil {
+		return err
+	}
+	if err := checkCallable("map", args[1]); err != nil {
+		return err
+	}
+
+	elems, err := collect(rt, "map", args[0])
+	if err != nil {
+		return err
+	}
+
+	result := make([]obj.Obj, len(elems))
+	for i, e := range elems {
+		if err := rt.step(); err != nil {
+			return err
+		}
+		mapped := applyIn(rt, args[1], []obj.Obj{e})
+		if isError(mapped) {
+			return mapped
+		}
+		result[i] = mapped
+	}
+	return &obj.Array{Elems: result}
+}
+
+func listFilter(rt *Runtime, args []obj.Obj) obj.Obj {
+	if err := checkArgs("filter", args, ANY, ANY); err != nil {
+		return err
+	}
+	if err := checkCallable("filter", args[1]); err != nil {
+		return err
+	}
+
+	elems, err := collect(rt, "filter", args[0])
+	if err != nil {
+		return err
+	}
+
+	result := []obj.Obj{}
+	for _, e := range elems {
+		if err := rt.step(); err != nil {
+			return err
+		}
+		keep := applyIn(rt, args[1], []obj.Obj{e})
+		if isError(keep) {
+			return keep
+		}
+		if isTruthy(keep) {
+			result = append(result, e)
+		}
+	}
+	return &obj.Array{Elems: result}
+}
+
+func listReduce(rt *Runtime, args []obj.Obj) obj.Obj {
+	if err := checkArgs("reduce", args, ANY, ANY, ANY); err != nil {
+		return err
+	}
+	if err := checkCallable("reduce", args[1]); err != nil {
+		return err
+	}
+
+	elems, err := collect(rt, "reduce", args[0])
+	if err != nil {
+		return err
+	}
+
+	acc := args[2]
+	for _, e := range elems {
+		if err := rt.step(); err != nil {
+			return err
+		}
+		acc = applyIn(rt, args[1], []obj.Obj{acc, e})
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+func listSort(rt *Runtime, args []obj.Obj) obj.Obj {
+	var less obj.Obj
+
+	if len(args) == 1 {
+		if err := checkArgs("sort", args, ANY); err != nil {
+			return err
+		}
+	} else {
+		if err := checkArgs("sort", args, ANY, ANY); err != nil {
+			return err
+		}
+		if err := checkCallable("sort", args[1]); err != nil {
+			return err
+		}
+		less = args[1]
+	}
+
+	elems, err := collect(rt, "sort", args[0])
+	if err != nil {
+		return err
+	}
+	return sortElems(rt, elems, less)
+}
+
+func listZip(rt *Runtime, args []obj.Obj) obj.Obj {
+	if err := checkArgs("zip", args, ANY, ANY); err != nil {
+		return err
+	}
+
+	left, err := collect(rt, "zip", args[0])
+	if err != nil {
+		return err
+	}
+	right, err := collect(rt, "zip", args[1])
+	if err != nil {
+		return err
+	}
+
+	n := len(left)
+	if len(right) < n {
+		n = len(right)
+	}
+
+	result := make([]obj.Obj, n)
+	for i := 0; i < n; i++ {
+		result[i] = &obj.Array{Elems: []obj.Obj{left[i], right[i]}}
+	}
+	return &obj.Array{Elems: result}
+}
+
+func listReverse(rt *Runtime, args []obj.Obj) obj.Obj {
+	if err := checkArgs("reverse", args, ANY); err != nil {
+		return err
+	}
+
+	elems, err := collect(rt, "reverse", args[0])
+	if err != nil {
+		return err
+	}
+
+	result := make([]obj.Obj, len(elems))
+	for i, e := range elems {
+		result[len(elems)-1-i] = e
+	}
+	return &obj.Array{Elems: result}
+}
+
+// sortElems sorts elems in place and returns them as an array. Without a
+// comparator the elements must all be integers or all be strings; a
+// comparator is called as less(a, b) and its result is tested for
+// truthiness. Each comparison counts a step of rt, if not nil.
+func sortElems(rt *Runtime, elems []obj.Obj, less obj.Obj) obj.Obj {
+	var err obj.Obj
+
+	if less == nil {
+		for _, e := range elems {
+			if e.Type() != elems[0].Type() ||
+				(e.Type() != obj.INTEGER_OBJ && e.Type() != obj.STRING_OBJ) {
+				return newError("argument to `sort` must be ARRAY of INTEGER or STRING, got %s in array",
+					e.Type())
+			}
+		}
+
+		sort.SliceStable(elems, func(i, j int) bool {
+			if err != nil {
+				return false
+			}
+			if stepErr := rt.step(); stepErr != nil {
+				err = stepErr
+				return false
+			}
+			switch a := elems[i].(type) {
+			case *obj.Integer:
+				return a.Value < elems[j].(*obj.Integer).Value
+			default:
+				return a.(*obj.String).Value < elems[j].(*obj.String).Value
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return &obj.Array{Elems: elems}
+	}
+
+	sort.SliceStable(elems, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		if stepErr := rt.step(); stepErr != nil {
+			err = stepErr
+			return false
+		}
+		result := applyIn(rt, less, []obj.Obj{elems[i], elems[j]})
+		if isError(result) {
+			err = result
+			return false
+		}
+		return isTruthy(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	return &obj.Array{Elems: elems}
+}
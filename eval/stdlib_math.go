@@ -0,0 +1,132 @@
+package eval
+
+import (
+	"math"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+var mathBuiltins = map[string]*obj.Builtin{
+	"abs": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("abs", args, obj.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			n := args[0].(*obj.Integer).Value
+			if n == math.MinInt64 {
+				return newError("integer overflow in `abs`, got %d", n)
+			}
+			if n < 0 {
+				n = -n
+			}
+			return &obj.Integer{Value: n}
+		},
+	},
+	"min": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			return integerFold("min", args, func(a, b int64) int64 {
+				if b < a {
+					return b
+				}
+				return a
+			})
+		},
+	},
+	"max": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			return integerFold("max", args, func(a, b int64) int64 {
+				if b > a {
+					return b
+				}
+				return a
+			})
+		},
+	},
+	"pow": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("pow", args, obj.INTEGER_OBJ, obj.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			base := args[0].(*obj.Integer).Value
+			exp := args[1].(*obj.Integer).Value
+			if exp < 0 {
+				return newError("argument to `pow` must not be negative, got %d", exp)
+			}
+
+			result := int64(1)
+			for ; exp > 0; exp >>= 1 {
+				if exp&1 == 1 {
+					result *= base
+				}
+				base *= base
+			}
+			return &obj.Integer{Value: result}
+		},
+	},
+	"sqrt": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("sqrt", args, obj.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			n := args[0].(*obj.Integer).Value
+			if n < 0 {
+				return newError("argument to `sqrt` must not be negative, got %d", n)
+			}
+
+			// Integers are the only numbers, so sqrt rounds down. The
+			// float estimate is corrected for large n where it is inexact,
+			// comparing by division so that squares near the top of the
+			// range do not overflow.
+			r := int64(math.Sqrt(float64(n)))
+			for r > 0 && r > n/r {
+				r--
+			}
+			for r+1 <= n/(r+1) {
+				r++
+			}
+			return &obj.Integer{Value: r}
+		},
+	},
+	"gcd": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("gcd", args, obj.INTEGER_OBJ, obj.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			a := args[0].(*obj.Integer).Value
+			b := args[1].(*obj.Integer).Value
+			for b != 0 {
+				a, b = b, a%b
+			}
+			if a < 0 {
+				a = -a
+			}
+			return &obj.Integer{Value: a}
+		},
+	},
+}
+
+func integerFold(name string, args []obj.Obj, fn func(a, b int64) int64) obj.Obj {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+
+	var result int64
+	for i, arg := range args {
+		n, ok := arg.(*obj.Integer)
+		if !ok {
+			return newError("argument to `%s` must be INTEGER, got %s",
+				name, arg.Type())
+		}
+		if i == 0 {
+			result = n.Value
+		} else {
+			result = fn(result, n.Value)
+		}
+	}
+
+	return &obj.Integer{Value: result}
+}
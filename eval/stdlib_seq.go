@@ -0,0 +1,97 @@
+package eval
+
+import (
+	"github.com/mdaisuke/monk/obj"
+)
+
+// seqBuiltins are the lazy counterparts of the list builtins. Each takes
+// any iterable and returns an obj.Iterator, so they chain without building
+// intermediate arrays; collect drains the result into an array.
+var seqBuiltins = map[string]*obj.Builtin{
+	"map": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("map", args, ANY, ANY); err != nil {
+				return err
+			}
+			if err := checkCallable("map", args[1]); err != nil {
+				return err
+			}
+
+			iterator, err := toIterator("map", args[0])
+			if err != nil {
+				return err
+			}
+
+			return iterator.Map(func(o obj.Obj) obj.Obj {
+				return applyFunction(args[1], []obj.Obj{o})
+			})
+		},
+	},
+	"filter": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("filter", args, ANY, ANY); err != nil {
+				return err
+			}
+			if err := checkCallable("filter", args[1]); err != nil {
+				return err
+			}
+
+			iterator, err := toIterator("filter", args[0])
+			if err != nil {
+				return err
+			}
+
+			return iterator.Filter(func(o obj.Obj) (bool, *obj.Error) {
+				keep := applyFunction(args[1], []obj.Obj{o})
+				if errObj, ok := keep.(*obj.Error); ok {
+					return false, errObj
+				}
+				return isTruthy(keep), nil
+			})
+		},
+	},
+	"take": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("take", args, ANY, obj.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			iterator, err := toIterator("take", args[0])
+			if err != nil {
+				return err
+			}
+
+			return iterator.Take(args[1].(*obj.Integer).Value)
+		},
+	},
+	"skip": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("skip", args, ANY, obj.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			iterator, err := toIterator("skip", args[0])
+			if err != nil {
+				return err
+			}
+
+			return iterator.Skip(args[1].(*obj.Integer).Value)
+		},
+	},
+	"collect": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj { return seqCollect(nil, args) },
+	},
+}
+
+func seqCollect(rt *Runtime, args []obj.Obj) obj.Obj {
+	if err := checkArgs("collect", args, ANY); err != nil {
+		return err
+	}
+
+	elems, err := collect(rt, "collect", args[0])
+	if err != nil {
+		return err
+	}
+
+	return &obj.Array{Elems: elems}
+}
@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"math"
+	"strings"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+var stringsBuiltins = map[string]*obj.Builtin{
+	"split": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("split", args, obj.STRING_OBJ, obj.STRING_OBJ); err != nil {
+				return err
+			}
+
+			s := args[0].(*obj.String).Value
+			sep := args[1].(*obj.String).Value
+
+			parts := strings.Split(s, sep)
+			elems := make([]obj.Obj, len(parts))
+			for i, p := range parts {
+				elems[i] = &obj.String{Value: p}
+			}
+			return &obj.Array{Elems: elems}
+		},
+	},
+	"join": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("join", args, obj.ARRAY_OBJ, obj.STRING_OBJ); err != nil {
+				return err
+			}
+
+			elems := args[0].(*obj.Array).Elems
+			sep := args[1].(*obj.String).Value
+
+			parts := make([]string, len(elems))
+			for i, e := range elems {
+				str, ok := e.(*obj.String)
+				if !ok {
+					return newError("argument to `join` must be ARRAY of STRING, got %s in array",
+						e.Type())
+				}
+				parts[i] = str.Value
+			}
+			return &obj.String{Value: strings.Join(parts, sep)}
+		},
+	},
+	"trim": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("trim", args, obj.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return &obj.String{Value: strings.TrimSpace(args[0].(*obj.String).Value)}
+		},
+	},
+	"replace": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("replace", args, obj.STRING_OBJ, obj.STRING_OBJ, obj.STRING_OBJ); err != nil {
+				return err
+			}
+
+			s := args[0].(*obj.String).Value
+			old := args[1].(*obj.String).Value
+			new := args[2].(*obj.String).Value
+			return &obj.String{Value: strings.ReplaceAll(s, old, new)}
+		},
+	},
+	"contains": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("contains", args, obj.STRING_OBJ, obj.STRING_OBJ); err != nil {
+				return err
+			}
+
+			s := args[0].(*obj.String).Value
+			sub := args[1].(*obj.String).Value
+			return nativeBoolToBooleanObj(strings.Contains(s, sub))
+		},
+	},
+	"upper": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("upper", args, obj.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return &obj.String{Value: strings.ToUpper(args[0].(*obj.String).Value)}
+		},
+	},
+	"lower": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("lower", args, obj.STRING_OBJ); err != nil {
+				return err
+			}
+
+			return &obj.String{Value: strings.ToLower(args[0].(*obj.String).Value)}
+		},
+	},
+	"repeat": &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if err := checkArgs("repeat", args, obj.STRING_OBJ, obj.INTEGER_OBJ); err != nil {
+				return err
+			}
+
+			s := args[0].(*obj.String).Value
+			n := args[1].(*obj.Integer).Value
+			if n < 0 {
+				return newError("argument to `repeat` must not be negative, got %d", n)
+			}
+			if len(s) > 0 && n > int64(math.MaxInt/len(s)) {
+				return newError("result of `repeat` would be too long: %d times %d bytes", n, len(s))
+			}
+			return &obj.String{Value: strings.Repeat(s, int(n))}
+		},
+	},
+}
@@ -0,0 +1,125 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+func TestStdlib(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "strings" as s; s.split("a,b,c", ",")`, []string{"a", "b", "c"}},
+		{`import "strings" as s; s.join(["a", "b"], "-")`, "a-b"},
+		{`import "strings" as s; s.trim("  hi ")`, "hi"},
+		{`import "strings" as s; s.replace("aXbX", "X", "-")`, "a-b-"},
+		{`import "strings" as s; s.contains("monkey", "key")`, true},
+		{`import "strings" as s; s.upper("abc")`, "ABC"},
+		{`import "strings" as s; s.lower("ABC")`, "abc"},
+		{`import "strings" as s; s.repeat("ab", 3)`, "ababab"},
+		{`import "strings" as s; s.upper(1)`, "argument to `upper` must be STRING, got INTEGER"},
+		{`import "strings" as s; s.split("a")`, "wrong number of arguments. got=1, want=2"},
+		{`import "strings" as s; s.join([1], ",")`, "argument to `join` must be ARRAY of STRING, got INTEGER in array"},
+		{`import "strings" as s; s.repeat("a", -1)`, "argument to `repeat` must not be negative, got -1"},
+		{`import "strings" as s; s.repeat("ab", 4611686018427387904)`, "result of `repeat` would be too long: 4611686018427387904 times 2 bytes"},
+		{`import "strings" as s; s.repeat("", 4611686018427387904)`, ""},
+
+		{`import "math" as m; m.abs(-5)`, 5},
+		{`import "math" as m; m.min(3, 1, 2)`, 1},
+		{`import "math" as m; m.max(3, 1, 2)`, 3},
+		{`import "math" as m; m.pow(2, 10)`, 1024},
+		{`import "math" as m; m.sqrt(17)`, 4},
+		{`import "math" as m; m.gcd(12, 18)`, 6},
+		{`import "math" as m; m.max()`, "wrong number of arguments. got=0, want at least 1"},
+		{`import "math" as m; m.min(1, "a")`, "argument to `min` must be INTEGER, got STRING"},
+		{`import "math" as m; m.sqrt(-1)`, "argument to `sqrt` must not be negative, got -1"},
+		{`import "math" as m; m.sqrt(9223372036854775807)`, 3037000499},
+		{`import "math" as m; m.sqrt(9223372030926249001)`, 3037000499},
+		{`import "math" as m; m.sqrt(9223372030926249000)`, 3037000498},
+		{`import "math" as m; m.abs(-9223372036854775807)`, 9223372036854775807},
+		{`import "math" as m; m.abs(-9223372036854775807 - 1)`, "integer overflow in `abs`, got -9223372036854775808"},
+
+		{`import "list"; list.map([1, 2, 3], fn(x) { x * 2 })`, []int64{2, 4, 6}},
+		{`import "list"; list.filter([1, 2, 3, 4], fn(x) { x > 2 })`, []int64{3, 4}},
+		{`import "list"; list.reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)`, 16},
+		{`import "list"; list.sort([3, 1, 2])`, []int64{1, 2, 3}},
+		{`import "list"; list.sort(["b", "c", "a"])`, []string{"a", "b", "c"}},
+		{`import "list"; list.sort([3, 1, 2], fn(a, b) { a > b })`, []int64{3, 2, 1}},
+		{`import "list"; list.zip([1, 2, 3], [4, 5])[1]`, []int64{2, 5}},
+		{`import "list"; import "seq"; seq.collect(list.range(3))`, []int64{0, 1, 2}},
+		{`import "list"; import "seq"; seq.collect(list.range(1, 4))`, []int64{1, 2, 3}},
+		{`import "list"; import "seq"; seq.collect(list.range(5, 0, -2))`, []int64{5, 3, 1}},
+		{`import "list"; len(list.range(5, 0, -2))`, 3},
+		{`import "list"; list.reverse([1, 2, 3])`, []int64{3, 2, 1}},
+		{`import "list"; list.map("ab", fn(c) { c + c })`, []string{"aa", "bb"}},
+		{`import "list"; list.map(1, fn(x) { x })`, "argument to `map` must be ITERABLE, got INTEGER"},
+		{`import "list"; list.map([1], 1)`, "argument to `map` must be FUNCTION, got INTEGER"},
+		{`import "list"; list.map([1], fn(x) { x + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{`import "list"; list.sort([1, "a"])`, "argument to `sort` must be ARRAY of INTEGER or STRING, got STRING in array"},
+		{`import "list"; list.range(1, 2, 0)`, "argument to `range` must not be zero, got step 0"},
+		{`import "list"; list.nope`, "module list has no export nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		case []int64:
+			array, ok := evaluated.(*obj.Array)
+			if !ok {
+				t.Errorf("obj is not Array. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elems) != len(expected) {
+				t.Errorf("wrong num of elems. want=%d, got=%d",
+					len(expected), len(array.Elems))
+				continue
+			}
+			for i, e := range expected {
+				testIntegerObj(t, array.Elems[i], e)
+			}
+		case []string:
+			array, ok := evaluated.(*obj.Array)
+			if !ok {
+				t.Errorf("obj is not Array. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elems) != len(expected) {
+				t.Errorf("wrong num of elems. want=%d, got=%d",
+					len(expected), len(array.Elems))
+				continue
+			}
+			for i, e := range expected {
+				testStringOrErrorObj(t, array.Elems[i], e)
+			}
+		}
+	}
+}
+
+func testStringOrErrorObj(t *testing.T, o obj.Obj, expected string) bool {
+	switch o := o.(type) {
+	case *obj.String:
+		if o.Value != expected {
+			t.Errorf("str.Value is not %q. got=%q", expected, o.Value)
+			return false
+		}
+	case *obj.Error:
+		if o.Message != expected {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				expected, o.Message)
+			return false
+		}
+	default:
+		t.Errorf("obj is not String or Error. got=%T(%+v)", o, o)
+		return false
+	}
+	return true
+}
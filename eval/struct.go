@@ -0,0 +1,65 @@
+package eval
+
+import (
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+func evalStructStmt(ss *ast.StructStmt, env *obj.Env) obj.Obj {
+	fields := make([]string, len(ss.Fields))
+	for i, f := range ss.Fields {
+		fields[i] = f.Value
+	}
+
+	if err := env.Set(ss.Name.Value, &obj.StructType{
+		Name:    ss.Name.Value,
+		Fields:  fields,
+		Methods: make(map[string]obj.Obj),
+	}); isError(err) {
+		return err
+	}
+
+	return nil
+}
+
+func newStruct(st *obj.StructType, args []obj.Obj) obj.Obj {
+	if len(args) != len(st.Fields) {
+		return newError("wrong number of arguments. got=%d, want=%d",
+			len(args), len(st.Fields))
+	}
+
+	values := make([]obj.Obj, len(args))
+	copy(values, args)
+
+	return &obj.Struct{StructType: st, Values: values}
+}
+
+func evalWithExp(we *ast.WithExp, env *obj.Env) obj.Obj {
+	left := Eval(we.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	s, ok := left.(*obj.Struct)
+	if !ok {
+		return newError("unknown op: %s with", left.Type())
+	}
+
+	values := make([]obj.Obj, len(s.Values))
+	copy(values, s.Values)
+
+	for i, name := range we.Names {
+		idx, ok := s.StructType.FieldIndex(name.Value)
+		if !ok {
+			return newError("no field %s on struct %s", name.Value, s.StructType.Name)
+		}
+
+		val := Eval(we.Values[i], env)
+		if isError(val) {
+			return val
+		}
+		values[idx] = val
+	}
+
+	return &obj.Struct{StructType: s.StructType, Values: values}
+}
@@ -0,0 +1,49 @@
+package eval
+
+import (
+	"testing"
+)
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"struct Point { x, y }; let p = Point(1, 2); p.x + p.y", 3},
+		{"struct Point { x, y }; Point(1, 2)", "Point{x: 1, y: 2}"},
+		{"struct Point { x, y }; Point", "struct Point { x, y }"},
+		{"struct Point { x, y }; let p = Point(1, 2); p with {x: 3}", "Point{x: 3, y: 2}"},
+		{"struct Point { x, y }; let p = Point(1, 2); let q = p with {x: 3}; p.x", 1},
+		{"struct Line { from, to }; struct Point { x, y }; Line(Point(0, 0), Point(1, 2)).to.y", 2},
+		{`struct Named { name }; Named("a") == Named("a")`, true},
+		{"struct Point { x, y }; Point(1, 2) == Point(1, 2)", true},
+		{"struct Point { x, y }; Point(1, 2) != Point(1, 3)", true},
+		{"struct Point { x, y }; Point(1, 2) == Point(1, 2) with {y: 3}", false},
+		{"struct A { x }; struct B { x }; A(1) == B(1)", false},
+		{"struct Line { from, to }; struct P { x }; Line(P(1), P(2)) == Line(P(1), P(2))", true},
+		{"struct Point { x, y }; Point(1, 2).z", "no field z on struct Point"},
+		{"struct Point { x, y }; Point(1, 2) with {z: 1}", "no field z on struct Point"},
+		{"struct Point { x, y }; Point(1)", "wrong number of arguments. got=1, want=2"},
+		{"struct Point { x, y }; Point(1, 2) + Point(1, 2)", "unknown op: STRUCT + STRUCT"},
+		{"5 with {x: 1}", "unknown op: INTEGER with"},
+		{"5.x", "no method x on INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			if isError(evaluated) {
+				testStringOrErrorObj(t, evaluated, expected)
+			} else if evaluated.Inspect() != expected {
+				t.Errorf("wrong inspect. expected=%q, got=%q",
+					expected, evaluated.Inspect())
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package eval
+
+import (
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+const tailCallObj obj.ObjType = "TAIL_CALL"
+
+// tailCall is what evalTail returns for a call in tail position: the
+// call has not been made, applyFunction makes it in place of the current
+// one. It never escapes applyFunction.
+type tailCall struct {
+	fn   *obj.Function
+	args []obj.Obj
+}
+
+func (tc *tailCall) Type() obj.ObjType { return tailCallObj }
+func (tc *tailCall) Inspect() string   { return "tail call" }
+
+// evalTail evaluates a statement or expression of a function body. A
+// return statement is always in tail position; the value of node is when
+// tail is set. Tail position reaches through the last statement of a
+// block and the branches of if and match, while returns are found
+// through any statement of the body's blocks and those branches.
+func evalTail(node ast.Node, env *obj.Env, tail bool) obj.Obj {
+	switch node := node.(type) {
+	case *ast.BlockStmt:
+		return evalTailBlockStmt(node, env, tail)
+	case *ast.ExpStmt:
+		return evalTail(node.Exp, env, tail)
+	case *ast.ReturnStmt:
+		val := evalTail(node.ReturnValue, env, true)
+		if isError(val) {
+			return val
+		}
+		if tc, ok := val.(*tailCall); ok {
+			return tc
+		}
+		return &obj.ReturnValue{Value: val}
+	case *ast.IfExp:
+		cond := Eval(node.Cond, env)
+		if isError(cond) {
+			return cond
+		}
+
+		if isTruthy(cond) {
+			return evalTail(node.Conseq, env, tail)
+		} else if node.Alt != nil {
+			return evalTail(node.Alt, env, tail)
+		}
+		return NULL
+	case *ast.MatchExp:
+		body, armEnv, err := selectMatchArm(node, env)
+		if err != nil {
+			return err
+		}
+		return evalTail(body, armEnv, tail)
+	case *ast.CallExp:
+		if !tail {
+			return Eval(node, env)
+		}
+
+		function, short := evalChain(node.Function, env)
+		if short || isError(function) {
+			return function
+		}
+		args := evalExps(node.Args, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+
+		if fn, ok := function.(*obj.Function); ok && !fn.Generator {
+			return &tailCall{fn: fn, args: args}
+		}
+		return applyFrom(env, function, args)
+	default:
+		return Eval(node, env)
+	}
+}
+
+func evalTailBlockStmt(block *ast.BlockStmt, env *obj.Env, tail bool) obj.Obj {
+	var result obj.Obj
+
+	if err := hoistFunctions(block.Stmts, env); err != nil {
+		return err
+	}
+
+	for i, stmt := range block.Stmts {
+		if err := step(env); err != nil {
+			return err
+		}
+
+		result = evalTail(stmt, env, tail && i == len(block.Stmts)-1)
+
+		if result != nil {
+			switch result.Type() {
+			case obj.RETURN_VALUE_OBJ, obj.ERROR_OBJ, tailCallObj:
+				return result
+			}
+		}
+	}
+
+	return result
+}
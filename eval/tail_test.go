@@ -0,0 +1,47 @@
+package eval
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestTailCalls(t *testing.T) {
+	// Far too small for 100000 nested calls to fit without tail calls.
+	defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn count(n) { if (n == 0) { 0 } else { count(n - 1) } } count(100000)", 0},
+		{"fn sum(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); } sum(100000, 0)", 5000050000},
+		{`
+		fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+		fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+		isOdd(100001)
+		`, true},
+		{`
+		enum L { Cons(head, tail), Nil }
+		fn build(n, acc) { if (n == 0) { acc } else { build(n - 1, Cons(n, acc)) } }
+		fn length(l, acc) { match (l) { Cons(_, t) => length(t, acc + 1), Nil => acc } }
+		length(build(50000, Nil), 0)
+		`, 50000},
+		{"let loop = fn(n) { if (n > 0) { return loop(n - 1); } 7 }; loop(100000)", 7},
+		{"fn f(n) { if (n == 0) { len([1, 2]) } else { f(n - 1) } } f(10)", 2},
+		{"fn f(n) { if (n == 0) { g(1, 2) } else { f(n - 1) } } fn g(x) { x } f(3)", "wrong number of arguments to `g`. got=2, want=1"},
+		{"fn f(n) { if (n == 0) { nope } else { f(n - 1) } } f(100000)", "identifier not found: nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObj(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObj(t, evaluated, expected)
+		case string:
+			testStringOrErrorObj(t, evaluated, expected)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package eval
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/obj"
+)
+
+// schedMu guards the state of every channel and task, and the counts of
+// every group. Under a single lock, waking a thread and counting it as
+// running again happen at once.
+var schedMu sync.Mutex
+
+// group is a set of threads among which deadlocks are detected: those of
+// a Runtime, or those of code running outside of any. A thread is an
+// evaluation started by the host, or a task spawned by one.
+type group struct {
+	running int // threads not blocked on a channel or task
+	blocked map[*waiter]struct{}
+	tasks   sync.WaitGroup  // tasks spawned in the group and not done
+	ctx     context.Context // wakes blocked threads when done; nil for global
+}
+
+func newGroup() *group {
+	return &group{blocked: make(map[*waiter]struct{})}
+}
+
+// global is the group of code running outside of any Runtime.
+var global = newGroup()
+
+func groupOf(rt *Runtime) *group {
+	if rt == nil {
+		return global
+	}
+	return rt.group
+}
+
+// enter counts a thread that starts running in g.
+func (g *group) enter() {
+	schedMu.Lock()
+	g.running++
+	schedMu.Unlock()
+}
+
+// exit uncounts a thread of g that is done.
+func (g *group) exit() {
+	schedMu.Lock()
+	g.running--
+	g.detect()
+	schedMu.Unlock()
+}
+
+// detect, with schedMu held, fails every blocked thread of g if none is
+// left running to unblock them. Once the context of g is done, threads
+// stop because of it rather than of each other.
+func (g *group) detect() {
+	if g.running > 0 {
+		return
+	}
+	err := newError("deadlock: all tasks are blocked")
+	if g.ctx != nil && g.ctx.Err() != nil {
+		err = newKindError(obj.CANCELED, "evaluation canceled: %s", g.ctx.Err())
+	}
+	for w := range g.blocked {
+		w.wake(-1, nil, err)
+	}
+}
+
+// waiter is a thread blocked until one of the cases it waits for can
+// proceed.
+type waiter struct {
+	group  *group
+	done   chan struct{} // closed once woken
+	chosen int           // the case that proceeded, -1 on error
+	value  obj.Obj
+	err    *obj.Error
+}
+
+func (g *group) newWaiter() *waiter {
+	return &waiter{group: g, done: make(chan struct{})}
+}
+
+// wait, with schedMu held, blocks the calling thread until w is woken,
+// or fails it with a CANCELED error once the context of g is done. It
+// releases schedMu.
+func (g *group) wait(w *waiter) {
+	g.blocked[w] = struct{}{}
+	g.running--
+	g.detect()
+	schedMu.Unlock()
+
+	var canceled <-chan struct{}
+	if g.ctx != nil {
+		canceled = g.ctx.Done()
+	}
+
+	select {
+	case <-w.done:
+	case <-canceled:
+		schedMu.Lock()
+		if w.blocked() {
+			w.wake(-1, nil, newKindError(obj.CANCELED, "evaluation canceled: %s", g.ctx.Err()))
+		}
+		schedMu.Unlock()
+	}
+}
+
+func (w *waiter) blocked() bool {
+	_, ok := w.group.blocked[w]
+	return ok
+}
+
+// wake, with schedMu held, resumes w with the outcome of its case i.
+func (w *waiter) wake(i int, value obj.Obj, err *obj.Error) {
+	delete(w.group.blocked, w)
+	w.group.running++
+	w.chosen, w.value, w.err = i, value, err
+	close(w.done)
+}
+
+// pending is case i of a waiter, queued on a channel or task.
+type pending struct {
+	w     *waiter
+	i     int
+	value obj.Obj // the value to send
+}
+
+// popLive removes and returns the first entry of q whose waiter is still
+// blocked. The others were woken by another of their cases.
+func popLive(q *[]pending) (pending, bool) {
+	for len(*q) > 0 {
+		p := (*q)[0]
+		*q = (*q)[1:]
+		if p.w.blocked() {
+			return p, true
+		}
+	}
+	return pending{}, false
+}
+
+type channel struct {
+	buf    []obj.Obj
+	closed bool
+	recvq  []pending
+	sendq  []pending
+}
+
+func newChannel(capacity int) *obj.Channel {
+	return &obj.Channel{Cap: capacity, State: &channel{}}
+}
+
+// comm is a send or a receive on a channel.
+type comm struct {
+	send  bool
+	ch    *obj.Channel
+	value obj.Obj
+}
+
+func newComm(op string, args []obj.Obj) (comm, *obj.Error) {
+	if op == "send" {
+		if err := checkArgs("send", args, obj.CHANNEL_OBJ, ANY); err != nil {
+			return comm{}, err
+		}
+		return comm{send: true, ch: args[0].(*obj.Channel), value: args[1]}, nil
+	}
+
+	if err := checkArgs("recv", args, obj.CHANNEL_OBJ); err != nil {
+		return comm{}, err
+	}
+	return comm{ch: args[0].(*obj.Channel)}, nil
+}
+
+// try, with schedMu held, performs c if it can proceed without blocking.
+// A receive from a closed and drained channel gets null.
+func (c comm) try() (value obj.Obj, ready bool, err *obj.Error) {
+	ch := c.ch.State.(*channel)
+
+	if c.send {
+		if ch.closed {
+			return nil, true, newError("send on closed channel")
+		}
+		if r, ok := popLive(&ch.recvq); ok {
+			r.w.wake(r.i, c.value, nil)
+			return NULL, true, nil
+		}
+		if len(ch.buf) < c.ch.Cap {
+			ch.buf = append(ch.buf, c.value)
+			return NULL, true, nil
+		}
+		return nil, false, nil
+	}
+
+	if len(ch.buf) > 0 {
+		value = ch.buf[0]
+		ch.buf = ch.buf[1:]
+		if s, ok := popLive(&ch.sendq); ok {
+			ch.buf = append(ch.buf, s.value)
+			s.w.wake(s.i, NULL, nil)
+		}
+		return value, true, nil
+	}
+	if s, ok := popLive(&ch.sendq); ok {
+		s.w.wake(s.i, NULL, nil)
+		return s.value, true, nil
+	}
+	if ch.closed {
+		return NULL, true, nil
+	}
+	return nil, false, nil
+}
+
+func (c comm) enqueue(w *waiter, i int) {
+	ch := c.ch.State.(*channel)
+	if c.send {
+		ch.sendq = append(ch.sendq, pending{w: w, i: i, value: c.value})
+	} else {
+		ch.recvq = append(ch.recvq, pending{w: w, i: i})
+	}
+}
+
+// selectComm performs the first of comms that can proceed and returns
+// its index and, for a receive, the value received. When none can, it
+// blocks until one can if block is set, and returns -1 otherwise.
+func (g *group) selectComm(comms []comm, block bool) (int, obj.Obj, *obj.Error) {
+	schedMu.Lock()
+
+	for i, c := range comms {
+		if value, ready, err := c.try(); ready {
+			schedMu.Unlock()
+			return i, value, err
+		}
+	}
+
+	if !block {
+		schedMu.Unlock()
+		return -1, nil, nil
+	}
+
+	w := g.newWaiter()
+	for i, c := range comms {
+		c.enqueue(w, i)
+	}
+	g.wait(w)
+
+	return w.chosen, w.value, w.err
+}
+
+func closeChannel(c *obj.Channel) *obj.Error {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+
+	ch := c.State.(*channel)
+	if ch.closed {
+		return newError("close of closed channel")
+	}
+	ch.closed = true
+
+	for r, ok := popLive(&ch.recvq); ok; r, ok = popLive(&ch.recvq) {
+		r.w.wake(r.i, NULL, nil)
+	}
+	for s, ok := popLive(&ch.sendq); ok; s, ok = popLive(&ch.sendq) {
+		s.w.wake(-1, nil, newError("send on closed channel"))
+	}
+	return nil
+}
+
+type task struct {
+	done    bool
+	result  obj.Obj
+	waiters []pending
+}
+
+// spawn calls fn with args in a new thread of g, for code running in env.
+// The task counts its calls apart from the thread spawning it.
+func (g *group) spawn(env *obj.Env, fn obj.Obj, args []obj.Obj) *obj.Task {
+	t := &task{}
+
+	taskEnv := obj.NewEnclosedEnv(env)
+	taskEnv.Set(threadKey, forkThread(threadOf(env, runOf(env))))
+
+	g.tasks.Add(1)
+	g.enter()
+
+	go func() {
+		defer g.tasks.Done()
+
+		result := safely(func() obj.Obj { return unwrapReturnValue(applyFrom(taskEnv, fn, args)) })
+
+		schedMu.Lock()
+		t.done, t.result = true, result
+		for p, ok := popLive(&t.waiters); ok; p, ok = popLive(&t.waiters) {
+			p.w.wake(p.i, result, nil)
+		}
+		g.running--
+		g.detect()
+		schedMu.Unlock()
+	}()
+
+	return &obj.Task{State: t}
+}
+
+// await blocks the calling thread of g until t is done, and returns its
+// result.
+func (g *group) await(t *obj.Task) obj.Obj {
+	st := t.State.(*task)
+
+	schedMu.Lock()
+	if st.done {
+		schedMu.Unlock()
+		return st.result
+	}
+
+	w := g.newWaiter()
+	st.waiters = append(st.waiters, pending{w: w})
+	g.wait(w)
+
+	if w.err != nil {
+		return w.err
+	}
+	return w.value
+}
+
+func evalSpawnExp(se *ast.SpawnExp, env *obj.Env) obj.Obj {
+	function := Eval(se.Call.Function, env)
+	if isError(function) {
+		return function
+	}
+	if !isCallable(function) {
+		return newError("not a function: %s", function.Type())
+	}
+
+	args := evalExps(se.Call.Args, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return groupOf(runOf(env)).spawn(env, function, args)
+}
+
+func evalSelectExp(se *ast.SelectExp, env *obj.Env) obj.Obj {
+	var comms []comm
+	var cases []*ast.SelectCase
+	var fallback *ast.SelectCase
+
+	for _, c := range se.Cases {
+		if c.Comm == nil {
+			fallback = c
+			continue
+		}
+
+		args := evalExps(c.Comm.Args, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		cm, err := newComm(c.Comm.Function.(*ast.Identifier).Value, args)
+		if err != nil {
+			return err
+		}
+
+		comms = append(comms, cm)
+		cases = append(cases, c)
+	}
+
+	i, value, err := groupOf(runOf(env)).selectComm(comms, fallback == nil)
+	if err != nil {
+		return err
+	}
+
+	chosen := fallback
+	caseEnv := obj.NewEnclosedEnv(env)
+	if i >= 0 {
+		chosen = cases[i]
+		if chosen.Binding != nil {
+			caseEnv.Set(chosen.Binding.Value, value)
+		}
+	}
+
+	return Eval(chosen.Body, caseEnv)
+}
+
+// threadBuiltins are the builtins that block the thread calling them.
+// Called from code running under a Runtime, they block in its group.
+var threadBuiltins map[*obj.Builtin]func(g *group, args []obj.Obj) obj.Obj
+
+func initThreadBuiltins() {
+	ops := map[string]func(g *group, args []obj.Obj) obj.Obj{
+		"send": func(g *group, args []obj.Obj) obj.Obj {
+			c, err := newComm("send", args)
+			if err != nil {
+				return err
+			}
+			if _, _, err := g.selectComm([]comm{c}, true); err != nil {
+				return err
+			}
+			return NULL
+		},
+		"recv": func(g *group, args []obj.Obj) obj.Obj {
+			c, err := newComm("recv", args)
+			if err != nil {
+				return err
+			}
+			_, value, err := g.selectComm([]comm{c}, true)
+			if err != nil {
+				return err
+			}
+			return value
+		},
+		"await": func(g *group, args []obj.Obj) obj.Obj {
+			if err := checkArgs("await", args, obj.TASK_OBJ); err != nil {
+				return err
+			}
+			return g.await(args[0].(*obj.Task))
+		},
+		"join": func(g *group, args []obj.Obj) obj.Obj {
+			if err := checkArgs("join", args, obj.ARRAY_OBJ); err != nil {
+				return err
+			}
+
+			tasks := args[0].(*obj.Array).Elems
+			for _, t := range tasks {
+				if t.Type() != obj.TASK_OBJ {
+					return newError("argument to `join` must be ARRAY of TASK, got %s in it", t.Type())
+				}
+			}
+
+			results := make([]obj.Obj, len(tasks))
+			for i, t := range tasks {
+				results[i] = g.await(t.(*obj.Task))
+				if isError(results[i]) {
+					return results[i]
+				}
+			}
+			return &obj.Array{Elems: results}
+		},
+	}
+
+	threadBuiltins = make(map[*obj.Builtin]func(g *group, args []obj.Obj) obj.Obj)
+	for name, op := range ops {
+		op := op
+		b := &obj.Builtin{Fn: func(args ...obj.Obj) obj.Obj { return op(global, args) }}
+		builtins[name] = b
+		threadBuiltins[b] = op
+	}
+
+	builtins["chan"] = &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if len(args) == 0 {
+				return newChannel(0)
+			}
+			if err := checkArgs("chan", args, obj.INTEGER_OBJ); err != nil {
+				return err
+			}
+			capacity := args[0].(*obj.Integer).Value
+			if capacity < 0 {
+				return newError("channel capacity must not be negative, got %d", capacity)
+			}
+			return newChannel(int(capacity))
+		},
+	}
+	builtins["close"] = &obj.Builtin{
+		Fn: func(args ...obj.Obj) obj.Obj {
+			if len(args) == 1 && args[0].Type() == obj.ITERATOR_OBJ {
+				args[0].(*obj.Iterator).Close()
+				return NULL
+			}
+			if err := checkArgs("close", args, obj.CHANNEL_OBJ); err != nil {
+				return err
+			}
+			if err := closeChannel(args[0].(*obj.Channel)); err != nil {
+				return err
+			}
+			return NULL
+		},
+	}
+}
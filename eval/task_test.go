@@ -0,0 +1,150 @@
+package eval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+func TestTasks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn fib(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } } await(spawn fib(15))", 610},
+		{"let t = spawn fn(x) { x * 2 }(21); await(t) + await(t)", 84},
+		{"fn sq(x) { x * x } let r = join([spawn sq(2), spawn sq(3), spawn sq(4)]); r[0] + r[1] + r[2]", 29},
+		{"len(join([]))", 0},
+		{"await(spawn fn() { 1 + true }())", "type mismatch: INTEGER + BOOLEAN"},
+		{"join([spawn len([]), 5])", "argument to `join` must be ARRAY of TASK, got INTEGER in it"},
+		{"spawn 5()", "not a function: INTEGER"},
+		{"await(5)", "argument to `await` must be TASK, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		testConcurrent(t, tt.input, tt.expected)
+	}
+}
+
+func TestChannels(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`
+		let c = chan();
+		fn produce(n) { if (n > 0) { send(c, n); produce(n - 1) } else { close(c) } }
+		fn consume(sum) { let v = recv(c); if (v == null) { sum } else { consume(sum + v) } }
+		spawn produce(100);
+		consume(0)
+		`, 5050},
+		{"let c = chan(2); send(c, 1); send(c, 2); recv(c) * 10 + recv(c)", 12},
+		{"let c = chan(1); send(c, 1); close(c); [recv(c), recv(c)]", "[1, null]"},
+		{"let c = chan(); spawn send(c, 7); recv(c)", 7},
+		{"let c = chan(); close(c); send(c, 1)", "send on closed channel"},
+		{"let c = chan(); close(c); close(c)", "close of closed channel"},
+		{"let c = chan(); spawn fn() { close(c) }(); send(c, 1)", "send on closed channel"},
+		{"chan(-1)", "channel capacity must not be negative, got -1"},
+		{"recv(5)", "argument to `recv` must be CHANNEL, got INTEGER"},
+		{"chan(3)", "channel(3)"},
+	}
+
+	for _, tt := range tests {
+		testConcurrent(t, tt.input, tt.expected)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let c = chan(); select { recv(c) as v => v, _ => -1 }", -1},
+		{"let c = chan(1); send(c, 5); select { recv(c) as v => v * 2, _ => -1 }", 10},
+		{"let c = chan(1); select { send(c, 3) => recv(c), _ => -1 }", 3},
+		{"let a = chan(); let b = chan(1); send(b, 2); select { recv(a) as v => v, recv(b) as v => v + 40 }", 42},
+		{"let a = chan(); let b = chan(); spawn send(b, 9); select { recv(a) => 0, recv(b) as v => { v } }", 9},
+		{"let c = chan(); close(c); select { recv(c) as v => v }", nil},
+		{"select { recv(5) => 1 }", "argument to `recv` must be CHANNEL, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		testConcurrent(t, tt.input, tt.expected)
+	}
+}
+
+func TestDeadlock(t *testing.T) {
+	tests := []string{
+		"let c = chan(); recv(c)",
+		"let c = chan(); send(c, 1)",
+		"select { }",
+		"let a = chan(); let b = chan(); spawn fn() { recv(a); send(b, 1) }(); recv(b)",
+		"let c = chan(); let t = spawn recv(c); await(t)",
+		"let c = chan(); join([spawn recv(c), spawn recv(c)])",
+		"let c = chan(); let r = recv >> len; r(c)",
+		`import "list"; let c = chan(); list.map([c], recv)`,
+	}
+
+	for _, input := range tests {
+		testConcurrent(t, input, "deadlock: all tasks are blocked")
+	}
+}
+
+func TestTaskDepthIsPerThread(t *testing.T) {
+	saved := MaxDepth
+	MaxDepth = 5000
+	defer func() { MaxDepth = saved }()
+
+	// Every task holds 3000 calls at once: 12000 between them.
+	input := `
+	let ready = chan();
+	let release = chan();
+	fn down(n) { if (n == 0) { send(ready, 1); recv(release); 0 } else { 1 + down(n - 1) } }
+	let ts = [spawn down(3000), spawn down(3000), spawn down(3000), spawn down(3000)];
+	recv(ready); recv(ready); recv(ready); recv(ready);
+	close(release);
+	join(ts)
+	`
+	testConcurrent(t, input, "[3000, 3000, 3000, 3000]")
+	testConcurrent(t, "fn down(n) { if (n == 0) { 0 } else { 1 + down(n - 1) } } await(spawn down(6000))",
+		"maximum recursion depth exceeded")
+}
+
+func TestTasksEndWithEvaluation(t *testing.T) {
+	input := "fn spin() { spin() } spawn spin(); let c = chan(); spawn recv(c); 1"
+
+	done := make(chan obj.Obj)
+	go func() { done <- testEvalWithLimits(input, Limits{}) }()
+
+	select {
+	case result := <-done:
+		testIntegerObj(t, result, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("evaluation waited on its tasks forever")
+	}
+}
+
+// testConcurrent checks input both outside of any Runtime and under one.
+func testConcurrent(t *testing.T, input string, expected interface{}) {
+	t.Helper()
+
+	for _, result := range []obj.Obj{testEval(input), testEvalWithLimits(input, Limits{})} {
+		switch expected := expected.(type) {
+		case int:
+			if !testIntegerObj(t, result, int64(expected)) {
+				t.Errorf("input: %s", input)
+			}
+		case nil:
+			testNullObj(t, result)
+		case string:
+			if err, ok := result.(*obj.Error); ok {
+				if err.Message != expected {
+					t.Errorf("%s: wrong error message. expected=%q, got=%q", input, expected, err.Message)
+				}
+			} else if result.Inspect() != expected {
+				t.Errorf("%s: expected %s. got=%s", input, expected, result.Inspect())
+			}
+		}
+	}
+}
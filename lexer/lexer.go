@@ -11,9 +11,17 @@ type Lexer struct {
 	ch      byte
 }
 
+// New returns a Lexer for input. A first line starting with #!, as in an
+// executable script, is skipped.
 func New(input string) *Lexer {
 	l := &Lexer{input: input}
 	l.readChar()
+
+	if l.ch == '#' && l.peekChar() == '!' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+	}
 	return l
 }
 
@@ -28,6 +36,10 @@ func (l *Lexer) NextToken() token.Token {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -50,7 +62,35 @@ func (l *Lexer) NextToken() token.Token {
 	case '<':
 		tok = newToken(token.LT, l.ch)
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.COMPOSE, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.PIPE, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '?':
+		switch l.peekChar() {
+		case '.':
+			l.readChar()
+			tok = token.Token{Type: token.OPT_DOT, Literal: "?."}
+		case '[':
+			l.readChar()
+			tok = token.Token{Type: token.OPT_BRACKET, Literal: "?["}
+		case '?':
+			l.readChar()
+			tok = token.Token{Type: token.NULLISH, Literal: "??"}
+		default:
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -65,6 +105,10 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = l.readString()
@@ -29,6 +29,16 @@ func TestNextToken(t *testing.T) {
 	"foobar"
 	"foo bar"
 	[1, 2];
+	import "lib/strings.mk" as s;
+	export let up = s.upper;
+	for (k in {"a": 1}) {}
+	struct P { x }
+	p with {x: 1}
+	impl P {}
+	enum E { A(x), B }
+	match e { A(x) => x, _ => 0 } is E
+	xs |> f >> g > h
+	null a?.b?[k] ?? ?
 	`
 
 	tests := []struct {
@@ -124,6 +134,92 @@ func TestNextToken(t *testing.T) {
 		{token.RBRACKET, "]"},
 		{token.SEMICOLON, ";"},
 
+		{token.IMPORT, "import"},
+		{token.STRING, "lib/strings.mk"},
+		{token.AS, "as"},
+		{token.IDENT, "s"},
+		{token.SEMICOLON, ";"},
+		{token.EXPORT, "export"},
+		{token.LET, "let"},
+		{token.IDENT, "up"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "s"},
+		{token.DOT, "."},
+		{token.IDENT, "upper"},
+		{token.SEMICOLON, ";"},
+
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "k"},
+		{token.IN, "in"},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+
+		{token.STRUCT, "struct"},
+		{token.IDENT, "P"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "p"},
+		{token.WITH, "with"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.IMPL, "impl"},
+		{token.IDENT, "P"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.ENUM, "enum"},
+		{token.IDENT, "E"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "A"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.COMMA, ","},
+		{token.IDENT, "B"},
+		{token.RBRACE, "}"},
+		{token.MATCH, "match"},
+		{token.IDENT, "e"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "A"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "x"},
+		{token.COMMA, ","},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "0"},
+		{token.RBRACE, "}"},
+		{token.IS, "is"},
+		{token.IDENT, "E"},
+		{token.IDENT, "xs"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.COMPOSE, ">>"},
+		{token.IDENT, "g"},
+		{token.GT, ">"},
+		{token.IDENT, "h"},
+		{token.NULL, "null"},
+		{token.IDENT, "a"},
+		{token.OPT_DOT, "?."},
+		{token.IDENT, "b"},
+		{token.OPT_BRACKET, "?["},
+		{token.IDENT, "k"},
+		{token.RBRACKET, "]"},
+		{token.NULLISH, "??"},
+		{token.ILLEGAL, "?"},
+
 		{token.EOF, ""},
 	}
 
@@ -142,3 +238,24 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestShebang(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.TokenType
+	}{
+		{"#!/usr/bin/env monk\nlet x = 1;", []token.TokenType{token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON, token.EOF}},
+		{"#!/usr/bin/env monk", []token.TokenType{token.EOF}},
+		{"1\n#!x", []token.TokenType{token.INT, token.ILLEGAL, token.BANG, token.IDENT, token.EOF}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected {
+				t.Fatalf("%q: tokens[%d] - tok.Type is not %q. got=%q", tt.input, i, expected, tok.Type)
+			}
+		}
+	}
+}
@@ -1,18 +1,128 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"path/filepath"
+	"strings"
 
+	"github.com/mdaisuke/monk/eval"
+	"github.com/mdaisuke/monk/monk"
+	"github.com/mdaisuke/monk/obj"
 	"github.com/mdaisuke/monk/repl"
 )
 
+const usage = `usage: monk                        start the REPL
+       monk --session <file>       start the REPL, keeping its session in file
+       monk run <file> [args...]   run a script
+       monk <file> [args...]       run a script, as from a #! line
+       monk -e <expr> [args...]    evaluate an expression and print it
+`
+
 func main() {
+	args := os.Args[1:]
+
+	var session string
+	if len(args) == 2 && args[0] == "--session" {
+		session, args = args[1], nil
+	}
+	if len(args) > 0 {
+		os.Exit(run(args, os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 	fmt.Printf("Hello %s!\n", user.Username)
-	repl.Start(os.Stdin, os.Stdout)
+	repl.StartSession(os.Stdin, os.Stdout, session)
+}
+
+// run runs the command line args, which do not include the program name,
+// and returns the exit status: the one passed to exit, 1 on a parse or
+// runtime error, or 2 on a usage error.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var name, src string
+	var expr bool
+
+	switch {
+	case len(args) == 0 || args[0] == "-h" || args[0] == "--help":
+		io.WriteString(stdout, usage)
+		return 0
+	case args[0] == "-e":
+		if len(args) < 2 {
+			io.WriteString(stderr, usage)
+			return 2
+		}
+		name, src, expr = "-e", args[1], true
+		args = args[2:]
+	case args[0] == "run" || !strings.HasPrefix(args[0], "-"):
+		if args[0] == "run" {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			io.WriteString(stderr, usage)
+			return 2
+		}
+
+		b, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintf(stderr, "monk: %s\n", err)
+			return 1
+		}
+		name, src = args[0], string(b)
+		args = args[1:]
+	default:
+		fmt.Fprintf(stderr, "monk: unknown flag %s\n%s", args[0], usage)
+		return 2
+	}
+
+	argv, _ := monk.ToObj(args)
+
+	// Scripts import modules next to them first.
+	searchPath := eval.Modules.SearchPath
+	if !expr {
+		searchPath = append([]string{filepath.Dir(name)}, searchPath...)
+	}
+
+	in := monk.New(
+		monk.WithStdin(stdin),
+		monk.WithStdout(stdout),
+		monk.WithStderr(stderr),
+		monk.WithGlobal("args", argv),
+		monk.WithLoader(eval.NewLoader(searchPath...)),
+	)
+
+	result, err := in.Eval(src)
+	if err != nil {
+		return report(stderr, name, err)
+	}
+
+	if expr && result != nil && result != eval.NULL {
+		fmt.Fprintln(stdout, result.Inspect())
+	}
+	return 0
+}
+
+// report writes err, got evaluating the source called name, to stderr and
+// returns the exit status it calls for.
+func report(stderr io.Writer, name string, err error) int {
+	var scriptErr *monk.Error
+	if !errors.As(err, &scriptErr) {
+		for _, msg := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(stderr, "%s: parse error: %s\n", name, msg)
+		}
+		return 1
+	}
+
+	if scriptErr.Kind == obj.EXIT {
+		return scriptErr.Code
+	}
+
+	inspected := (&obj.Error{Message: scriptErr.Message, Trace: scriptErr.Trace}).Inspect()
+	fmt.Fprintf(stderr, "%s: %s\n", name, inspected)
+	return 1
 }
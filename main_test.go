@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScript(t *testing.T, dir, name, src string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "greet.mk", `export fn greet(name) { "hello " + name }`)
+	script := writeScript(t, dir, "main.mk", `#!/usr/bin/env monk
+import "greet.mk";
+puts(greet.greet(args[0]));
+puts(len(args));
+`)
+	failing := writeScript(t, dir, "fail.mk", "fn f() { 1 + true }\nf()")
+	broken := writeScript(t, dir, "broken.mk", "let = 1;")
+	exiting := writeScript(t, dir, "exit.mk", `puts("before"); exit(3); puts("after")`)
+
+	tests := []struct {
+		args           []string
+		expectedStatus int
+		expectedOut    string
+		expectedErr    string
+	}{
+		{[]string{"run", script, "world", "x"}, 0, "hello world\n2\n", ""},
+		{[]string{script}, 1, "", "main.mk: ERROR: type mismatch: STRING + NULL\n    at greet\n"},
+		{[]string{"-e", "1 + 2"}, 0, "3\n", ""},
+		{[]string{"-e", "args", "a", "b"}, 0, "[a, b]\n", ""},
+		{[]string{"-e", `puts("hi")`}, 0, "hi\n", ""},
+		{[]string{"-e", "exit()"}, 0, "", ""},
+		{[]string{"-e", "let x = 1"}, 0, "", ""},
+		{[]string{"-e", "fn f(x){x}"}, 0, "", ""},
+		{[]string{"-e", ""}, 0, "", ""},
+		{[]string{"run", exiting}, 3, "before\n", ""},
+		{[]string{"run", failing}, 1, "", "fail.mk: ERROR: type mismatch: INTEGER + BOOLEAN\n    at f\n"},
+		{[]string{"run", broken}, 1, "", "broken.mk: parse error: expected next token to be IDENT, got== instead\n"},
+		{[]string{"run", filepath.Join(dir, "missing.mk")}, 1, "", "no such file or directory"},
+		{[]string{"-e"}, 2, "", "usage:"},
+		{[]string{"run"}, 2, "", "usage:"},
+		{[]string{"-x"}, 2, "", "unknown flag -x"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(tt.args, strings.NewReader(""), &stdout, &stderr)
+
+		if status != tt.expectedStatus {
+			t.Errorf("%v: wrong status. expected=%d, got=%d (%s)", tt.args, tt.expectedStatus, status, stderr.String())
+		}
+		if stdout.String() != tt.expectedOut {
+			t.Errorf("%v: wrong output. expected=%q, got=%q", tt.args, tt.expectedOut, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), tt.expectedErr) || tt.expectedErr == "" && stderr.Len() != 0 {
+			t.Errorf("%v: wrong error output. expected=%q, got=%q", tt.args, tt.expectedErr, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package monk
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mdaisuke/monk/eval"
+	"github.com/mdaisuke/monk/obj"
+)
+
+func TestCall(t *testing.T) {
+	in := New()
+	if _, err := in.Eval(`
+	let offset = 10;
+	let handler = fn(x) { x + offset };
+	let early = fn(x) { if (x > 0) { return "positive"; } "other" };
+	struct Point { x, y }
+	`); err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []obj.Obj
+		expected string
+	}{
+		{"handler", []obj.Obj{&obj.Integer{Value: 5}}, "15"},
+		{"early", []obj.Obj{&obj.Integer{Value: 1}}, "positive"},
+		{"early", []obj.Obj{&obj.Integer{Value: -1}}, "other"},
+		{"Point", []obj.Obj{&obj.Integer{Value: 1}, &obj.Integer{Value: 2}}, "Point{x: 1, y: 2}"},
+	}
+
+	for _, tt := range tests {
+		fn, ok := in.Get(tt.name)
+		if !ok {
+			t.Fatalf("%s is not bound", tt.name)
+		}
+
+		result, err := in.Call(fn, tt.args...)
+		if err != nil {
+			t.Errorf("Call(%s): %s", tt.name, err)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("Call(%s) wrong. want=%q, got=%q", tt.name, tt.expected, result.Inspect())
+		}
+	}
+
+	result, err := in.Call(MustFunc("add", func(a, b int) int { return a + b }),
+		&obj.Integer{Value: 1}, &obj.Integer{Value: 2})
+	if err != nil || result.Inspect() != "3" {
+		t.Errorf("Call(builtin) wrong. got=%v, err=%v", result, err)
+	}
+
+	if _, err := in.Call(&obj.Integer{Value: 1}); err == nil || err.Error() != "not a function: INTEGER" {
+		t.Errorf("Call(1) wrong error. got=%v", err)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	in := New(WithLimits(eval.Limits{MaxSteps: 1000}))
+	if _, err := in.Eval(`
+	fn check(x) { if (x < 0) { -x } else { x + "!" } }
+	fn validate(x) { let r = check(x); r }
+	fn spin() { spin() }
+	`); err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+
+	validate, _ := in.Get("validate")
+	_, err := in.Call(validate, &obj.Integer{Value: 1})
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("error is not *Error. got=%T(%v)", err, err)
+	}
+	if e.Message != "type mismatch: INTEGER + STRING" || e.Kind != "" {
+		t.Errorf("wrong error. got=%+v", e)
+	}
+	if !reflect.DeepEqual(e.Trace, []string{"check", "validate"}) {
+		t.Errorf("wrong trace. got=%v", e.Trace)
+	}
+
+	spin, _ := in.Get("spin")
+	_, err = in.Call(spin)
+	if !errors.As(err, &e) || e.Kind != obj.STEP_LIMIT {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	// The budget is spent per call, not per Interpreter.
+	if result, err := in.Call(validate, &obj.Integer{Value: -2}); err != nil || result.Inspect() != "2" {
+		t.Errorf("Call(validate) wrong. got=%v, err=%v", result, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	unlimited := New()
+	if _, err := unlimited.Eval("fn spin() { spin() }"); err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	spin, _ = unlimited.Get("spin")
+	_, err = unlimited.CallContext(ctx, spin)
+	if !errors.As(err, &e) || e.Kind != obj.CANCELED {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
+
+func TestCallFromBuiltin(t *testing.T) {
+	var in *Interpreter
+	var calls int
+	in = New(WithBuiltin("each", MustFunc("each", func(n int, fn obj.Obj) error {
+		for i := 0; i < n; i++ {
+			if _, err := in.Call(fn, &obj.Integer{Value: int64(i)}); err != nil {
+				return err
+			}
+			calls++
+		}
+		return nil
+	})), WithLimits(eval.Limits{MaxSteps: 50}))
+
+	if _, err := in.Eval("each(3, fn(i) { i })"); err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	if calls != 3 {
+		t.Errorf("wrong number of calls. got=%d", calls)
+	}
+
+	// Callbacks share the budget of the evaluation that made them.
+	_, err := in.Eval("each(1000, fn(i) { i })")
+	if err == nil || err.Error() != "step limit exceeded" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
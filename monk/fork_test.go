@@ -0,0 +1,87 @@
+package monk
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+func TestFork(t *testing.T) {
+	in := New()
+	if _, err := in.Eval("let greet = fn(name) { \"hi \" + name }; let count = 0;"); err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		fork := in.Fork()
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			src := fmt.Sprintf("let count = %d; greet(\"n\" + \"%d\")", i, i)
+			result, err := fork.Eval(src)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if want := fmt.Sprintf("hi n%d", i); result.Inspect() != want {
+				errs <- fmt.Errorf("wrong result. want=%q, got=%q", want, result.Inspect())
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// Bindings made by forks stay theirs, and in goes on evaluating.
+	result, err := in.Eval("let count = count + 1; count")
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	testInteger(t, result, 1)
+}
+
+func TestForksShareStdin(t *testing.T) {
+	var lines strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&lines, "%d\n", i)
+	}
+	in := New(WithStdin(strings.NewReader(lines.String())))
+
+	var wg sync.WaitGroup
+	counts := make(chan int64, 8)
+	for i := 0; i < 8; i++ {
+		fork := in.Fork()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := fork.Eval("fn count(n) { if (readline() == null) { n } else { count(n + 1) } } count(0)")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			counts <- result.(*obj.Integer).Value
+		}()
+	}
+	wg.Wait()
+	close(counts)
+
+	var total int64
+	for n := range counts {
+		total += n
+	}
+	if total != 20000 {
+		t.Errorf("forks read %d lines, want 20000", total)
+	}
+}
@@ -0,0 +1,538 @@
+package monk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mdaisuke/monk/eval"
+	"github.com/mdaisuke/monk/obj"
+)
+
+var (
+	objType   = reflect.TypeOf((*obj.Obj)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ToObj converts a Go value to the obj value scripts see:
+//
+//   - nil, and nil pointers, slices and maps, become null
+//   - integers become integers; floats do too, if they are whole numbers,
+//     as the language has no floats
+//   - strings and bools become strings and booleans
+//   - slices and arrays become arrays
+//   - maps become hashes, if their keys convert to hashable values, with
+//     their keys sorted
+//   - structs become hashes keyed by field name, which a `monk:"name"` tag
+//     overrides and `monk:"-"` omits
+//   - errors become strings holding their message: an error value would
+//     abort the script reading it
+//   - obj values are returned as they are
+//
+// A value that contains itself, through pointers, maps or slices, cannot
+// be converted.
+func ToObj(v interface{}) (obj.Obj, error) {
+	if v == nil {
+		return eval.NULL, nil
+	}
+	return toObj(reflect.ValueOf(v), make(map[visit]bool))
+}
+
+// visit is a pointer, map or slice being converted, with the type it is
+// converted as and, for a slice, its length.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// toObj converts v, within the values in seen, which are being converted
+// and so must not be met again.
+func toObj(v reflect.Value, seen map[visit]bool) (obj.Obj, error) {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return eval.NULL, nil
+	}
+	if v.Type().Implements(objType) {
+		return v.Interface().(obj.Obj), nil
+	}
+	if v.Type().Implements(errorType) {
+		return &obj.String{Value: v.Interface().(error).Error()}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !v.IsNil() {
+			key := visit{ptr: v.Pointer(), typ: v.Type()}
+			if v.Kind() == reflect.Slice {
+				key.len = v.Len()
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("cyclic value of type %s", v.Type())
+			}
+			seen[key] = true
+			defer delete(seen, key)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return toObj(v.Elem(), seen)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &obj.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows INTEGER", v.Uint())
+		}
+		return &obj.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return nil, fmt.Errorf("%g is not a whole number in INTEGER range", f)
+		}
+		return &obj.Integer{Value: int64(f)}, nil
+	case reflect.String:
+		return &obj.String{Value: v.String()}, nil
+	case reflect.Bool:
+		if v.Bool() {
+			return eval.TRUE, nil
+		}
+		return eval.FALSE, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return eval.NULL, nil
+		}
+		elems := make([]obj.Obj, v.Len())
+		for i := range elems {
+			elem, err := toObj(v.Index(i), seen)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %s", i, err)
+			}
+			elems[i] = elem
+		}
+		return &obj.Array{Elems: elems}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return eval.NULL, nil
+		}
+		hash := obj.NewHash()
+		for _, k := range sortedKeys(v) {
+			key, err := toObj(k, seen)
+			if err != nil {
+				return nil, err
+			}
+			hashKey, ok := obj.AsHashable(key)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			value, err := toObj(v.MapIndex(k), seen)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %s", key.Inspect(), err)
+			}
+			hash.Set(hashKey, value)
+		}
+		return hash, nil
+	case reflect.Struct:
+		hash := obj.NewHash()
+		for _, f := range fieldsOf(v.Type()) {
+			value, err := toObj(v.FieldByIndex(f.index), seen)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %s", f.name, err)
+			}
+			hash.Set(&obj.String{Value: f.name}, value)
+		}
+		return hash, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %s", v.Type())
+	}
+}
+
+// sortedKeys returns the keys of the map v in order, so that a map
+// converts to the same hash every time. Keys with no natural order, such
+// as structs, come in Go's random order after the others.
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := reflect.Indirect(keys[i]), reflect.Indirect(keys[j])
+		if a.Kind() == reflect.Interface {
+			a = a.Elem()
+		}
+		if b.Kind() == reflect.Interface {
+			b = b.Elem()
+		}
+
+		if ra, rb := keyRank(a), keyRank(b); ra != rb {
+			return ra < rb
+		}
+		switch keyRank(a) {
+		case 0:
+			return !a.Bool() && b.Bool()
+		case 1:
+			return a.Int() < b.Int()
+		case 2:
+			return a.Uint() < b.Uint()
+		case 3:
+			return a.Float() < b.Float()
+		case 4:
+			return a.String() < b.String()
+		default:
+			return false
+		}
+	})
+	return keys
+}
+
+// keyRank groups the kinds of map keys that compare with each other.
+func keyRank(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Bool:
+		return 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return 1
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return 2
+	case reflect.Float32, reflect.Float64:
+		return 3
+	case reflect.String:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// FromObj stores o in the Go value target points to, converting it the
+// other way round from ToObj. Integers also convert to floats, error
+// values to errors, hashes with string keys and structs to Go structs,
+// and anything to an obj.Obj or an empty interface, which receives int64,
+// string, bool, nil, []interface{} or map[string]interface{} values.
+func FromObj(o obj.Obj, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	return fromObj(o, v.Elem())
+}
+
+func fromObj(o obj.Obj, v reflect.Value) error {
+	t := v.Type()
+
+	if t == objType {
+		v.Set(reflect.ValueOf(&o).Elem())
+		return nil
+	}
+	if t == errorType {
+		switch o := o.(type) {
+		case *obj.Null:
+			v.Set(reflect.Zero(t))
+		case *obj.Error:
+			v.Set(reflect.ValueOf(errors.New(o.Message)))
+		case *obj.String:
+			v.Set(reflect.ValueOf(errors.New(o.Value)))
+		default:
+			return mismatch(o, "STRING or ERROR")
+		}
+		return nil
+	}
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		native, err := toNative(o)
+		if err != nil {
+			return err
+		}
+		if native == nil {
+			v.Set(reflect.Zero(t))
+		} else {
+			v.Set(reflect.ValueOf(native))
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if o.Type() == obj.NULL_OBJ {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		elem := reflect.New(t.Elem())
+		if err := fromObj(o, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := o.(*obj.Integer)
+		if !ok {
+			return mismatch(o, obj.INTEGER_OBJ)
+		}
+		if v.OverflowInt(i.Value) {
+			return fmt.Errorf("%d overflows %s", i.Value, t)
+		}
+		v.SetInt(i.Value)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := o.(*obj.Integer)
+		if !ok {
+			return mismatch(o, obj.INTEGER_OBJ)
+		}
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return fmt.Errorf("%d overflows %s", i.Value, t)
+		}
+		v.SetUint(uint64(i.Value))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		i, ok := o.(*obj.Integer)
+		if !ok {
+			return mismatch(o, obj.INTEGER_OBJ)
+		}
+		v.SetFloat(float64(i.Value))
+		return nil
+	case reflect.String:
+		s, ok := o.(*obj.String)
+		if !ok {
+			return mismatch(o, obj.STRING_OBJ)
+		}
+		v.SetString(s.Value)
+		return nil
+	case reflect.Bool:
+		b, ok := o.(*obj.Boolean)
+		if !ok {
+			return mismatch(o, obj.BOOLEAN_OBJ)
+		}
+		v.SetBool(b.Value)
+		return nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && o.Type() == obj.NULL_OBJ {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		arr, ok := o.(*obj.Array)
+		if !ok {
+			return mismatch(o, obj.ARRAY_OBJ)
+		}
+		if t.Kind() == reflect.Array && len(arr.Elems) != t.Len() {
+			return fmt.Errorf("cannot convert ARRAY of %d elements to %s", len(arr.Elems), t)
+		}
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(arr.Elems), len(arr.Elems)))
+		}
+		for i, elem := range arr.Elems {
+			if err := fromObj(elem, v.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %s", i, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		if o.Type() == obj.NULL_OBJ {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		hash, ok := o.(*obj.Hash)
+		if !ok {
+			return mismatch(o, obj.HASH_OBJ)
+		}
+		m := reflect.MakeMapWithSize(t, len(hash.Keys))
+		for _, hk := range hash.Keys {
+			pair := hash.Pairs[hk]
+			key := reflect.New(t.Key()).Elem()
+			if err := fromObj(pair.Key, key); err != nil {
+				return fmt.Errorf("key %s: %s", pair.Key.Inspect(), err)
+			}
+			value := reflect.New(t.Elem()).Elem()
+			if err := fromObj(pair.Value, value); err != nil {
+				return fmt.Errorf("key %s: %s", pair.Key.Inspect(), err)
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Struct:
+		if o.Type() != obj.HASH_OBJ && o.Type() != obj.STRUCT_OBJ {
+			return mismatch(o, obj.HASH_OBJ)
+		}
+		for _, f := range fieldsOf(t) {
+			value, ok := fieldOf(o, f.name)
+			if !ok {
+				continue
+			}
+			if err := fromObj(value, v.FieldByIndex(f.index)); err != nil {
+				return fmt.Errorf("field %s: %s", f.name, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("cannot convert %s to %s", o.Type(), t)
+	}
+}
+
+// toNative converts o to the Go value an empty interface receives.
+func toNative(o obj.Obj) (interface{}, error) {
+	switch o := o.(type) {
+	case *obj.Null:
+		return nil, nil
+	case *obj.Integer:
+		return o.Value, nil
+	case *obj.String:
+		return o.Value, nil
+	case *obj.Boolean:
+		return o.Value, nil
+	case *obj.Error:
+		return errors.New(o.Message), nil
+	case *obj.Array:
+		elems := make([]interface{}, len(o.Elems))
+		for i, elem := range o.Elems {
+			native, err := toNative(elem)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %s", i, err)
+			}
+			elems[i] = native
+		}
+		return elems, nil
+	case *obj.Hash:
+		m := make(map[string]interface{}, len(o.Keys))
+		for _, hk := range o.Keys {
+			pair := o.Pairs[hk]
+			key, ok := pair.Key.(*obj.String)
+			if !ok {
+				return nil, fmt.Errorf("cannot convert HASH with %s keys", pair.Key.Type())
+			}
+			native, err := toNative(pair.Value)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %s", key.Value, err)
+			}
+			m[key.Value] = native
+		}
+		return m, nil
+	default:
+		return o, nil
+	}
+}
+
+type field struct {
+	name  string
+	index []int
+}
+
+// fieldsOf returns the exported fields of t under the names scripts see.
+func fieldsOf(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("monk"); ok {
+			tag = strings.Split(tag, ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, field{name: name, index: f.Index})
+	}
+	return fields
+}
+
+// fieldOf returns the value called name in a hash or struct.
+func fieldOf(o obj.Obj, name string) (obj.Obj, bool) {
+	switch o := o.(type) {
+	case *obj.Hash:
+		return o.Get(&obj.String{Value: name})
+	case *obj.Struct:
+		for i, f := range o.StructType.Fields {
+			if f == name {
+				return o.Values[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func mismatch(o obj.Obj, want obj.ObjType) error {
+	return fmt.Errorf("must be %s, got %s", want, o.Type())
+}
+
+// Func wraps fn, any Go func, as a builtin called name. Arguments are
+// converted with FromObj after checking their number, and results with
+// ToObj: none become null, one becomes the value, more become an array. A
+// last result of type error becomes an error value when not nil.
+func Func(name string, fn interface{}) (*obj.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("cannot wrap %T as a builtin", fn)
+	}
+	t := v.Type()
+
+	return &obj.Builtin{Fn: func(args ...obj.Obj) obj.Obj {
+		in, err := funcArgs(name, t, args)
+		if err != nil {
+			return err
+		}
+		return funcResults(name, t, v.Call(in))
+	}}, nil
+}
+
+// MustFunc is like Func but panics if fn is not a func.
+func MustFunc(name string, fn interface{}) *obj.Builtin {
+	b, err := Func(name, fn)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func funcArgs(name string, t reflect.Type, args []obj.Obj) ([]reflect.Value, *obj.Error) {
+	want := t.NumIn()
+	if t.IsVariadic() && len(args) < want-1 || !t.IsVariadic() && len(args) != want {
+		return nil, &obj.Error{Message: fmt.Sprintf(
+			"wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), want)}
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var at reflect.Type
+		if t.IsVariadic() && i >= want-1 {
+			at = t.In(want - 1).Elem()
+		} else {
+			at = t.In(i)
+		}
+
+		in[i] = reflect.New(at).Elem()
+		if err := fromObj(arg, in[i]); err != nil {
+			return nil, &obj.Error{Message: fmt.Sprintf(
+				"argument %d to `%s` %s", i+1, name, err)}
+		}
+	}
+	return in, nil
+}
+
+func funcResults(name string, t reflect.Type, out []reflect.Value) obj.Obj {
+	if n := len(out); n > 0 && t.Out(n-1) == errorType {
+		if err := out[n-1]; !err.IsNil() {
+			return &obj.Error{Message: err.Interface().(error).Error()}
+		}
+		out = out[:n-1]
+	}
+
+	results := make([]obj.Obj, len(out))
+	for i, v := range out {
+		result, err := toObj(v, make(map[visit]bool))
+		if err != nil {
+			return &obj.Error{Message: fmt.Sprintf("result of `%s`: %s", name, err)}
+		}
+		results[i] = result
+	}
+
+	switch len(results) {
+	case 0:
+		return eval.NULL
+	case 1:
+		return results[0]
+	default:
+		return &obj.Array{Elems: results}
+	}
+}
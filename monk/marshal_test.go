@@ -0,0 +1,268 @@
+package monk
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+type point struct {
+	X      int    `monk:"x"`
+	Y      int    `monk:"y"`
+	Label  string `monk:"label,omitempty"`
+	Secret string `monk:"-"`
+	hidden int
+}
+
+func TestToObj(t *testing.T) {
+	var nilPtr *point
+	var nilSlice []int
+	shared := &point{X: 1}
+
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{nilPtr, "null"},
+		{nilSlice, "null"},
+		{42, "42"},
+		{int8(-3), "-3"},
+		{uint16(7), "7"},
+		{2.0, "2"},
+		{"hi", "hi"},
+		{true, "true"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{map[string]int{"a": 1}, "{a: 1}"},
+		{map[string]int{"c": 3, "a": 1, "b": 2, "d": 4}, "{a: 1, b: 2, c: 3, d: 4}"},
+		{map[int]string{3: "c", -1: "z", 2: "b", 10: "j"}, "{-1: z, 2: b, 3: c, 10: j}"},
+		{map[interface{}]int{"x": 1, 2: 2, true: 3, uint(7): 4}, "{true: 3, 2: 2, 7: 4, x: 1}"},
+		{point{X: 1, Y: 2, Label: "p", Secret: "s", hidden: 3}, "{x: 1, y: 2, label: p}"},
+		{&point{X: 1}, "{x: 1, y: 0, label: }"},
+		{[]interface{}{1, "a", nil}, "[1, a, null]"},
+		{errors.New("boom"), "boom"},
+		{[]error{errors.New("a"), nil}, "[a, null]"},
+		{[]*point{shared, shared}, "[{x: 1, y: 0, label: }, {x: 1, y: 0, label: }]"},
+		{&obj.Integer{Value: 5}, "5"},
+	}
+
+	for _, tt := range tests {
+		o, err := ToObj(tt.input)
+		if err != nil {
+			t.Errorf("ToObj(%#v): %s", tt.input, err)
+			continue
+		}
+		if o.Inspect() != tt.expected {
+			t.Errorf("ToObj(%#v) wrong. want=%q, got=%q", tt.input, tt.expected, o.Inspect())
+		}
+	}
+}
+
+type node struct {
+	Value int   `monk:"value"`
+	Next  *node `monk:"next"`
+}
+
+func cyclicNode() *node {
+	n := &node{Value: 1}
+	n.Next = n
+	return n
+}
+
+func cyclicMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	m["self"] = m
+	return m
+}
+
+func cyclicSlice() []interface{} {
+	s := make([]interface{}, 1)
+	s[0] = s
+	return s
+}
+
+func TestToObjErrors(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{1.5, "1.5 is not a whole number in INTEGER range"},
+		{uint64(1 << 63), "9223372036854775808 overflows INTEGER"},
+		{make(chan int), "cannot convert chan int"},
+		{map[string]func(){"f": nil}, "key f: cannot convert func()"},
+		{cyclicNode(), "field next: cyclic value of type *monk.node"},
+		{cyclicMap(), "key self: cyclic value of type map[string]interface {}"},
+		{cyclicSlice(), "index 0: cyclic value of type []interface {}"},
+	}
+
+	for _, tt := range tests {
+		_, err := ToObj(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("ToObj(%T) wrong error. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestFromObj(t *testing.T) {
+	in := New()
+	eval := func(src string) obj.Obj {
+		t.Helper()
+		o, err := in.Eval(src)
+		if err != nil {
+			t.Fatalf("Eval(%q): %s", src, err)
+		}
+		return o
+	}
+
+	var i int
+	if err := FromObj(eval("40 + 2"), &i); err != nil || i != 42 {
+		t.Errorf("int: got=%d, err=%v", i, err)
+	}
+
+	var f float64
+	if err := FromObj(eval("3"), &f); err != nil || f != 3 {
+		t.Errorf("float64: got=%g, err=%v", f, err)
+	}
+
+	var s string
+	if err := FromObj(eval(`"a" + "b"`), &s); err != nil || s != "ab" {
+		t.Errorf("string: got=%q, err=%v", s, err)
+	}
+
+	var xs []int
+	if err := FromObj(eval("[1, 2, 3]"), &xs); err != nil || len(xs) != 3 || xs[2] != 3 {
+		t.Errorf("[]int: got=%v, err=%v", xs, err)
+	}
+
+	var m map[string]bool
+	if err := FromObj(eval(`{"a": true, "b": false}`), &m); err != nil || !m["a"] || m["b"] {
+		t.Errorf("map: got=%v, err=%v", m, err)
+	}
+
+	var p point
+	if err := FromObj(eval(`{"x": 1, "y": 2, "Secret": "s"}`), &p); err != nil || p.X != 1 || p.Y != 2 || p.Secret != "" {
+		t.Errorf("struct from hash: got=%+v, err=%v", p, err)
+	}
+
+	var q *point
+	if err := FromObj(eval("struct P { x, y } P(3, 4)"), &q); err != nil || q == nil || q.X != 3 || q.Y != 4 {
+		t.Errorf("struct from struct: got=%+v, err=%v", q, err)
+	}
+
+	var any interface{}
+	if err := FromObj(eval(`{"a": [1, "b", null]}`), &any); err != nil {
+		t.Errorf("interface: %s", err)
+	} else if got := any.(map[string]interface{})["a"].([]interface{}); got[0] != int64(1) || got[1] != "b" || got[2] != nil {
+		t.Errorf("interface: got=%#v", any)
+	}
+
+	// Errors come back from the strings they convert to.
+	var e error
+	errObj, _ := ToObj(errors.New("boom"))
+	if err := FromObj(errObj, &e); err != nil || e == nil || e.Error() != "boom" {
+		t.Errorf("error: got=%v, err=%v", e, err)
+	}
+
+	var o obj.Obj
+	if err := FromObj(eval("fn(x) { x }"), &o); err != nil || o.Type() != obj.FUNCTION_OBJ {
+		t.Errorf("obj.Obj: got=%v, err=%v", o, err)
+	}
+}
+
+func TestFromObjErrors(t *testing.T) {
+	var i8 int8
+	var u uint
+	var s string
+	var xs []int
+	var p point
+
+	tests := []struct {
+		input    obj.Obj
+		target   interface{}
+		expected string
+	}{
+		{&obj.Integer{Value: 300}, &i8, "300 overflows int8"},
+		{&obj.Integer{Value: -1}, &u, "-1 overflows uint"},
+		{&obj.Integer{Value: 1}, &s, "must be STRING, got INTEGER"},
+		{&obj.Array{Elems: []obj.Obj{&obj.String{Value: "a"}}}, &xs, "index 0: must be INTEGER, got STRING"},
+		{&obj.Integer{Value: 1}, &p, "must be HASH, got INTEGER"},
+		{&obj.Integer{Value: 1}, s, "target must be a non-nil pointer, got string"},
+	}
+
+	for _, tt := range tests {
+		err := FromObj(tt.input, tt.target)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("FromObj(%s, %T) wrong error. want=%q, got=%v", tt.input.Inspect(), tt.target, tt.expected, err)
+		}
+	}
+}
+
+func TestFunc(t *testing.T) {
+	in := New(
+		WithBuiltin("add", MustFunc("add", func(a, b int) int { return a + b })),
+		WithBuiltin("join", MustFunc("join", func(sep string, parts ...string) string {
+			return strings.Join(parts, sep)
+		})),
+		WithBuiltin("div", MustFunc("div", func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		})),
+		WithBuiltin("origin", MustFunc("origin", func() point { return point{Label: "o"} })),
+		WithBuiltin("norm", MustFunc("norm", func(p point) int { return p.X*p.X + p.Y*p.Y })),
+		WithBuiltin("swap", MustFunc("swap", func(a, b string) (string, string) { return b, a })),
+		WithBuiltin("nop", MustFunc("nop", func() {})),
+	)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(1, 2)", "3"},
+		{`join("-", "a", "b", "c")`, "a-b-c"},
+		{`join("-")`, ""},
+		{"div(7, 2)", "3"},
+		{`origin()["label"]`, "o"},
+		{`norm({"x": 3, "y": 4})`, "25"},
+		{`swap("a", "b")`, "[b, a]"},
+		{"nop()", "null"},
+		{"[1, 2].map(fn(x) { add(x, x) })", "[2, 4]"},
+	}
+
+	for _, tt := range tests {
+		o, err := in.Eval(tt.input)
+		if err != nil {
+			t.Errorf("%s: %s", tt.input, err)
+			continue
+		}
+		if o.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. want=%q, got=%q", tt.input, tt.expected, o.Inspect())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(1)", "wrong number of arguments to `add`. got=1, want=2"},
+		{`add(1, "2")`, "argument 2 to `add` must be INTEGER, got STRING"},
+		{"join()", "wrong number of arguments to `join`. got=0, want=2"},
+		{"div(1, 0)", "division by zero"},
+		{"norm(1)", "argument 1 to `norm` must be HASH, got INTEGER"},
+	}
+
+	for _, tt := range errorTests {
+		_, err := in.Eval(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%s: wrong error. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	if _, err := Func("x", 42); err == nil {
+		t.Errorf("Func accepted a non-func")
+	}
+}
@@ -0,0 +1,254 @@
+// Package monk embeds the monk interpreter in Go programs.
+package monk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mdaisuke/monk/eval"
+	"github.com/mdaisuke/monk/lexer"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/parser"
+)
+
+// Interpreter evaluates source against globals of its own. Its builtins,
+// I/O and modules are its own too, so interpreters with different
+// capabilities can coexist in one process. An Interpreter runs one
+// evaluation at a time; to evaluate concurrently, Fork it once per
+// goroutine.
+type Interpreter struct {
+	env     *obj.Env
+	runtime *eval.Runtime
+
+	builtins   map[string]*obj.Builtin
+	methods    []method
+	limits     eval.Limits
+	searchPath []string
+
+	shared *obj.Env // the globals last frozen by Fork
+	dirty  bool     // whether env may have bindings made since
+}
+
+// Option configures an Interpreter made by New.
+type Option func(*config)
+
+type config struct {
+	builtins map[string]*obj.Builtin
+	extra    map[string]*obj.Builtin
+	methods  []method
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	globals  map[string]obj.Obj
+	loader   *eval.Loader
+	limits   eval.Limits
+}
+
+// WithBuiltins makes builtins the only ones scripts can call, in place of
+// eval.DefaultBuiltins and the I/O builtins. The stdlib modules and
+// methods scripts can use are only those made of them; see
+// eval.NewRuntime.
+func WithBuiltins(builtins map[string]*obj.Builtin) Option {
+	return func(c *config) { c.builtins = builtins }
+}
+
+// WithBuiltin adds fn to the builtins under name.
+func WithBuiltin(name string, fn *obj.Builtin) Option {
+	return func(c *config) { c.extra[name] = fn }
+}
+
+type method struct {
+	t    obj.ObjType
+	name string
+	fn   *obj.Builtin
+}
+
+// WithMethod makes fn callable as a method named name on every value of
+// type t, in the scripts of this Interpreter only.
+func WithMethod(t obj.ObjType, name string, fn *obj.Builtin) Option {
+	return func(c *config) { c.methods = append(c.methods, method{t, name, fn}) }
+}
+
+// WithStdin sets where readline reads from, os.Stdin by default.
+func WithStdin(r io.Reader) Option {
+	return func(c *config) { c.stdin = r }
+}
+
+// WithStdout sets where puts writes to, os.Stdout by default.
+func WithStdout(w io.Writer) Option {
+	return func(c *config) { c.stdout = w }
+}
+
+// WithStderr sets where warn writes to, os.Stderr by default.
+func WithStderr(w io.Writer) Option {
+	return func(c *config) { c.stderr = w }
+}
+
+// WithGlobal binds name to val in the globals of the Interpreter.
+func WithGlobal(name string, val obj.Obj) Option {
+	return func(c *config) { c.globals[name] = val }
+}
+
+// WithLoader makes imports go through l, which must not be shared with
+// another Interpreter. By default each Interpreter gets a Loader of its
+// own on the search path of eval.Modules.
+func WithLoader(l *eval.Loader) Option {
+	return func(c *config) { c.loader = l }
+}
+
+// WithLimits holds each evaluation to limits.
+func WithLimits(limits eval.Limits) Option {
+	return func(c *config) { c.limits = limits }
+}
+
+// New returns an Interpreter configured by opts.
+func New(opts ...Option) *Interpreter {
+	c := &config{
+		stdin:   os.Stdin,
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+		extra:   make(map[string]*obj.Builtin),
+		globals: make(map[string]obj.Obj),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	builtins := make(map[string]*obj.Builtin)
+	if c.builtins == nil {
+		merge(builtins, eval.DefaultBuiltins())
+		merge(builtins, eval.IOBuiltins(c.stdin, c.stdout, c.stderr))
+	} else {
+		merge(builtins, c.builtins)
+	}
+	merge(builtins, c.extra)
+
+	env := obj.NewEnv()
+	for name, val := range c.globals {
+		env.Set(name, val)
+	}
+
+	searchPath := eval.Modules.SearchPath
+	if c.loader != nil {
+		searchPath = c.loader.SearchPath
+	}
+
+	in := &Interpreter{
+		env:        env,
+		builtins:   builtins,
+		methods:    c.methods,
+		limits:     c.limits,
+		searchPath: searchPath,
+	}
+	in.runtime = in.newRuntime(c.loader)
+	return in
+}
+
+// newRuntime returns a Runtime with the builtins, methods and limits of
+// in whose imports go through loader.
+func (in *Interpreter) newRuntime(loader *eval.Loader) *eval.Runtime {
+	rt := eval.NewRuntime(in.builtins, loader, in.limits)
+	for _, m := range in.methods {
+		rt.RegisterMethod(m.t, m.name, m.fn)
+	}
+	return rt
+}
+
+// Fork returns an Interpreter starting with the globals of in, for use on
+// another goroutine. The globals are frozen and shared rather than copied:
+// from then on in, like the fork, makes its bindings on top of them. The
+// fork has the builtins, methods and limits of in, and a loader of its
+// own on the same search path. The builtins are shared, so the writers
+// given for puts and warn must be safe for concurrent use. Fork must not
+// be called during an evaluation of in.
+func (in *Interpreter) Fork() *Interpreter {
+	if in.shared == nil || in.dirty {
+		in.env.Freeze()
+		in.shared = in.env
+		in.env = obj.NewEnclosedEnv(in.shared)
+		in.dirty = false
+	}
+
+	fork := &Interpreter{
+		env:        obj.NewEnclosedEnv(in.shared),
+		builtins:   in.builtins,
+		methods:    in.methods,
+		limits:     in.limits,
+		searchPath: in.searchPath,
+	}
+	fork.runtime = fork.newRuntime(eval.NewLoader(in.searchPath...))
+	return fork
+}
+
+func merge(dst, src map[string]*obj.Builtin) {
+	for name, fn := range src {
+		dst[name] = fn
+	}
+}
+
+// Eval evaluates src in the globals of in. Bindings it makes stay for
+// later evaluations.
+func (in *Interpreter) Eval(src string) (obj.Obj, error) {
+	return in.EvalContext(context.Background(), src)
+}
+
+// EvalContext is like Eval but gives up once ctx is done.
+func (in *Interpreter) EvalContext(ctx context.Context, src string) (obj.Obj, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	in.dirty = true
+	return result(in.runtime.Eval(ctx, program, in.env))
+}
+
+// Call calls fn, a function or builtin got from a script of in, with
+// args. It is held to the limits of in like an evaluation, and may be made
+// from a builtin during one.
+func (in *Interpreter) Call(fn obj.Obj, args ...obj.Obj) (obj.Obj, error) {
+	return in.CallContext(context.Background(), fn, args...)
+}
+
+// CallContext is like Call but gives up once ctx is done.
+func (in *Interpreter) CallContext(ctx context.Context, fn obj.Obj, args ...obj.Obj) (obj.Obj, error) {
+	return result(in.runtime.Apply(ctx, in.env, fn, args...))
+}
+
+func result(o obj.Obj) (obj.Obj, error) {
+	if err, ok := o.(*obj.Error); ok {
+		return nil, &Error{Kind: err.Kind, Message: err.Message, Trace: err.Trace, Code: err.Code}
+	}
+	return o, nil
+}
+
+// Error is a script error returned by Eval and Call.
+type Error struct {
+	// Kind is empty for ordinary errors, or one of the kinds of obj.Error
+	// for limit violations and cancellation.
+	Kind    obj.ErrorKind
+	Message string
+	// Trace names the functions the error propagated out of, innermost
+	// first.
+	Trace []string
+	// Code is the status the script passed to exit, for errors of kind
+	// obj.EXIT.
+	Code int
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Get returns the global called name.
+func (in *Interpreter) Get(name string) (obj.Obj, bool) {
+	return in.env.Get(name)
+}
+
+// Set binds the global called name to val.
+func (in *Interpreter) Set(name string, val obj.Obj) {
+	in.dirty = true
+	in.env.Set(name, val)
+}
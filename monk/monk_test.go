@@ -0,0 +1,212 @@
+package monk
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mdaisuke/monk/eval"
+	"github.com/mdaisuke/monk/obj"
+)
+
+func TestInterpreterIsolation(t *testing.T) {
+	a := New()
+	b := New()
+
+	if _, err := a.Eval("let x = 1;"); err != nil {
+		t.Fatalf("a.Eval: %s", err)
+	}
+	if _, err := b.Eval("x"); err == nil || err.Error() != "identifier not found: x" {
+		t.Fatalf("b sees a's globals. err=%v", err)
+	}
+
+	result, err := a.Eval("x + 1")
+	if err != nil {
+		t.Fatalf("a.Eval: %s", err)
+	}
+	testInteger(t, result, 2)
+}
+
+func TestInterpreterBuiltins(t *testing.T) {
+	double := &obj.Builtin{Fn: func(args ...obj.Obj) obj.Obj {
+		return &obj.Integer{Value: args[0].(*obj.Integer).Value * 2}
+	}}
+	defaults := eval.DefaultBuiltins()
+	none := map[string]*obj.Builtin{}
+	some := map[string]*obj.Builtin{"len": defaults["len"], "strings.repeat": defaults["strings.repeat"]}
+
+	tests := []struct {
+		opts     []Option
+		input    string
+		expected interface{}
+	}{
+		{nil, `len("abc")`, 3},
+		{[]Option{WithBuiltin("double", double)}, "double(21)", 42},
+		{[]Option{WithBuiltin("double", double)}, `len("abc")`, 3},
+		{[]Option{WithBuiltins(map[string]*obj.Builtin{"double": double})}, "double(2)", 4},
+		{[]Option{WithBuiltins(map[string]*obj.Builtin{"double": double})}, `len("abc")`, "identifier not found: len"},
+		{[]Option{WithBuiltins(map[string]*obj.Builtin{})}, `puts("x")`, "identifier not found: puts"},
+		{nil, "double(2)", "identifier not found: double"},
+		{[]Option{WithBuiltins(none)}, `"ab".repeat(3)`, "no method repeat on STRING"},
+		{[]Option{WithBuiltins(none)}, "[1, 2].len()", "no method len on ARRAY"},
+		{[]Option{WithBuiltins(none)}, `import "strings"`, "module not found: strings"},
+		{[]Option{WithBuiltins(some)}, `"ab".repeat(3).len()`, 6},
+		{[]Option{WithBuiltins(some)}, `import "strings"; len(strings.repeat("ab", 2))`, 4},
+		{[]Option{WithBuiltins(some)}, `import "strings"; strings.upper("ab")`, "module strings has no export upper"},
+		{[]Option{WithBuiltins(some)}, `"ab".upper()`, "no method upper on STRING"},
+		{[]Option{WithBuiltins(defaults)}, `import "list"; list.range(3).len() + "ab".repeat(2).len()`, 7},
+	}
+
+	for _, tt := range tests {
+		result, err := New(tt.opts...).Eval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			if err != nil {
+				t.Errorf("%s: %s", tt.input, err)
+				continue
+			}
+			testInteger(t, result, int64(expected))
+		case string:
+			if err == nil || err.Error() != expected {
+				t.Errorf("%s: wrong error. want=%q, got=%v", tt.input, expected, err)
+			}
+		}
+	}
+}
+
+func TestInterpreterMethods(t *testing.T) {
+	shout := &obj.Builtin{Fn: func(args ...obj.Obj) obj.Obj {
+		return &obj.String{Value: strings.ToUpper(args[0].(*obj.String).Value) + "!"}
+	}}
+
+	in := New(WithMethod(obj.STRING_OBJ, "shout", shout))
+	for _, it := range []*Interpreter{in, in.Fork()} {
+		result, err := it.Eval(`"x".shout()`)
+		if err != nil {
+			t.Fatalf("Eval: %s", err)
+		}
+		if result.Inspect() != "X!" {
+			t.Errorf("wrong result. want=%q, got=%q", "X!", result.Inspect())
+		}
+	}
+
+	eval.RegisterMethod(obj.STRING_OBJ, "whisper", shout)
+
+	for _, input := range []string{`"x".shout()`, `"x".whisper()`} {
+		_, err := New().Eval(input)
+		if err == nil || !strings.HasPrefix(err.Error(), "no method") {
+			t.Errorf("%s: method leaked into another Interpreter. err=%v", input, err)
+		}
+	}
+}
+
+func TestInterpreterIO(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	in := New(
+		WithStdin(strings.NewReader("alice\nbob\n")),
+		WithStdout(&stdout),
+		WithStderr(&stderr),
+	)
+
+	_, err := in.Eval(`
+	let a = readline();
+	let b = readline();
+	puts("hello " + a, "hello " + b);
+	warn(readline() ?? "eof");
+	`)
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+
+	if got := stdout.String(); got != "hello alice\nhello bob\n" {
+		t.Errorf("wrong stdout. got=%q", got)
+	}
+	if got := stderr.String(); got != "eof\n" {
+		t.Errorf("wrong stderr. got=%q", got)
+	}
+}
+
+func TestInterpreterGlobals(t *testing.T) {
+	in := New(WithGlobal("answer", &obj.Integer{Value: 42}))
+
+	result, err := in.Eval("answer")
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	testInteger(t, result, 42)
+
+	in.Set("answer", &obj.Integer{Value: 7})
+	result, err = in.Eval("let doubled = answer * 2; doubled")
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	testInteger(t, result, 14)
+
+	doubled, ok := in.Get("doubled")
+	if !ok {
+		t.Fatalf("doubled is not bound")
+	}
+	testInteger(t, doubled, 14)
+}
+
+func TestInterpreterLoader(t *testing.T) {
+	dir := t.TempDir()
+	src := "export let greet = fn(name) { \"hi \" + name };"
+	if err := os.WriteFile(filepath.Join(dir, "greet.mk"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	in := New(WithLoader(eval.NewLoader(dir)))
+	result, err := in.Eval(`import "greet.mk"; greet.greet("bob")`)
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	if s, ok := result.(*obj.String); !ok || s.Value != "hi bob" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+
+	if _, err := New().Eval(`import "greet.mk"`); err == nil {
+		t.Errorf("module found without the loader")
+	}
+}
+
+func TestInterpreterLimits(t *testing.T) {
+	in := New(WithLimits(eval.Limits{MaxSteps: 100}))
+
+	if _, err := in.Eval("fn f() { f() } f()"); err == nil || err.Error() != "step limit exceeded" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	// Each evaluation starts with the full budget.
+	result, err := in.Eval("1 + 1")
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	testInteger(t, result, 2)
+}
+
+func TestInterpreterParseErrors(t *testing.T) {
+	_, err := New().Eval("let = 1;")
+	if err == nil {
+		t.Fatalf("no error")
+	}
+	if !strings.Contains(err.Error(), "expected next token to be IDENT") {
+		t.Errorf("wrong error. got=%q", err)
+	}
+}
+
+func testInteger(t *testing.T, o obj.Obj, expected int64) {
+	t.Helper()
+
+	i, ok := o.(*obj.Integer)
+	if !ok {
+		t.Errorf("obj is not Integer. got=%T(%+v)", o, o)
+		return
+	}
+	if i.Value != expected {
+		t.Errorf("wrong value. want=%d, got=%d", expected, i.Value)
+	}
+}
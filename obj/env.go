@@ -1,5 +1,11 @@
 package obj
 
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
 func NewEnclosedEnv(outer *Env) *Env {
 	env := NewEnv()
 	env.outer = outer
@@ -11,20 +17,106 @@ func NewEnv() *Env {
 	return &Env{store: s, outer: nil}
 }
 
+// Env binds names to values. An Env is safe for concurrent use, as by the
+// tasks of a script, which share the scope they were spawned in. Envs
+// shared by many goroutines are best frozen: reading a frozen Env takes
+// no lock. Each goroutine then gets an Env of its own enclosing it, where
+// its writes go.
 type Env struct {
-	store map[string]Obj
-	outer *Env
+	mu     sync.RWMutex
+	store  map[string]Obj
+	outer  *Env
+	frozen bool
 }
 
 func (e *Env) Get(name string) (Obj, bool) {
-	o, ok := e.store[name]
+	o, ok := e.lookup(name)
 	if !ok && e.outer != nil {
 		o, ok = e.outer.Get(name)
 	}
 	return o, ok
 }
 
+// Resolve returns the Env name is bound in, e or one of its outer Envs,
+// or nil if there is none.
+func (e *Env) Resolve(name string) *Env {
+	for ; e != nil; e = e.outer {
+		if _, ok := e.lookup(name); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// Set binds name to val in e and returns val, or an *Error if e is
+// frozen.
 func (e *Env) Set(name string, val Obj) Obj {
+	if e.frozen {
+		return frozenError(name)
+	}
+	e.mu.Lock()
 	e.store[name] = val
+	e.mu.Unlock()
 	return val
 }
+
+// Delete removes name from e itself; bindings in outer Envs are untouched.
+// It returns an *Error if e is frozen, and nil otherwise.
+func (e *Env) Delete(name string) *Error {
+	if e.frozen {
+		return frozenError(name)
+	}
+	e.mu.Lock()
+	delete(e.store, name)
+	e.mu.Unlock()
+	return nil
+}
+
+func frozenError(name string) *Error {
+	return &Error{Message: fmt.Sprintf("cannot bind %s: the environment is frozen", name)}
+}
+
+// Outer returns the Env e encloses, or nil if there is none.
+func (e *Env) Outer() *Env {
+	return e.outer
+}
+
+// Names returns the names bound in e itself, sorted.
+func (e *Env) Names() []string {
+	if !e.frozen {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+	}
+
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the value bound to name in e itself.
+func (e *Env) lookup(name string) (Obj, bool) {
+	if e.frozen {
+		o, ok := e.store[name]
+		return o, ok
+	}
+
+	e.mu.RLock()
+	o, ok := e.store[name]
+	e.mu.RUnlock()
+	return o, ok
+}
+
+// Freeze makes e read-only, so that goroutines read it without locking.
+// It must be called before e is shared. Its outer Envs must not be
+// written to afterwards either.
+func (e *Env) Freeze() {
+	e.frozen = true
+}
+
+// Frozen reports whether e is read-only.
+func (e *Env) Frozen() bool {
+	return e.frozen
+}
@@ -0,0 +1,139 @@
+package obj
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Equal reports whether a and b are the same value. Scalars and ranges
+// compare by value, arrays, hashes, structs and enum variants compare
+// deeply, and everything else, functions included, by identity.
+func Equal(a, b Obj) bool {
+	switch a := a.(type) {
+	case *Integer:
+		b, ok := b.(*Integer)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Range:
+		b, ok := b.(*Range)
+		return ok && *a == *b
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && equalElems(a.Elems, b.Elems)
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for hk, pair := range a.Pairs {
+			other, ok := b.Pairs[hk]
+			if !ok || !Equal(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		b, ok := b.(*Struct)
+		return ok && a.StructType == b.StructType && equalElems(a.Values, b.Values)
+	case *Variant:
+		b, ok := b.(*Variant)
+		return ok && a.VariantType == b.VariantType && equalElems(a.Values, b.Values)
+	default:
+		return a == b
+	}
+}
+
+func equalElems(a, b []Obj) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// AsHashable returns o as a hash key if it can be one: a scalar, or an
+// array, struct or variant made only of such values. Values compared by
+// identity, and hashes themselves, cannot be keys.
+//
+// Keys obey the contract of Equal: equal values have equal HashKeys, so a
+// key built anew finds the pair stored under an equal one.
+func AsHashable(o Obj) (Hashable, bool) {
+	switch o := o.(type) {
+	case *Integer, *Boolean, *Null, *String, *Range:
+		return o.(Hashable), true
+	case *Array:
+		return o, allHashable(o.Elems)
+	case *Struct:
+		return o, allHashable(o.Values)
+	case *Variant:
+		return o, allHashable(o.Values)
+	default:
+		return nil, false
+	}
+}
+
+func allHashable(elems []Obj) bool {
+	for _, e := range elems {
+		if _, ok := AsHashable(e); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Null) HashKey() HashKey {
+	return HashKey{Type: n.Type()}
+}
+
+func (r *Range) HashKey() HashKey {
+	return HashKey{Type: r.Type(), Value: hashValues(r.Start, r.End, r.Step)}
+}
+
+func (a *Array) HashKey() HashKey {
+	return HashKey{Type: a.Type(), Value: hashElems("", a.Elems)}
+}
+
+func (s *Struct) HashKey() HashKey {
+	return HashKey{Type: s.Type(), Value: hashElems(s.StructType.Name, s.Values)}
+}
+
+func (v *Variant) HashKey() HashKey {
+	name := v.VariantType.Enum.Name + "." + v.VariantType.Name
+	return HashKey{Type: v.Type(), Value: hashElems(name, v.Values)}
+}
+
+// hashElems combines name with the keys of elems. Elements that cannot be
+// keys contribute only their type, which keeps equal values hashing alike.
+func hashElems(name string, elems []Obj) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	for _, e := range elems {
+		h.Write([]byte(e.Type()))
+		if k, ok := e.(Hashable); ok {
+			binary.Write(h, binary.LittleEndian, k.HashKey().Value)
+		}
+	}
+
+	return h.Sum64()
+}
+
+func hashValues(values ...int64) uint64 {
+	h := fnv.New64a()
+	for _, v := range values {
+		binary.Write(h, binary.LittleEndian, v)
+	}
+	return h.Sum64()
+}
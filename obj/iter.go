@@ -0,0 +1,191 @@
+package obj
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Iter produces the elements of a sequence one at a time. Next reports
+// false once the sequence is exhausted. An *Error element aborts the
+// iteration; consumers stop and return it.
+type Iter interface {
+	Next() (Obj, bool)
+}
+
+// Iterable is implemented by values that for-in loops and the list
+// builtins can walk. Each call to Iter starts a fresh pass unless the
+// value is itself a single-pass Iterator.
+type Iterable interface {
+	Obj
+	Iter() Iter
+}
+
+type IterFunc func() (Obj, bool)
+
+func (f IterFunc) Next() (Obj, bool) { return f() }
+
+func (a *Array) Iter() Iter {
+	i := 0
+	return IterFunc(func() (Obj, bool) {
+		if i >= len(a.Elems) {
+			return nil, false
+		}
+		i++
+		return a.Elems[i-1], true
+	})
+}
+
+// Iter walks the characters of the string, each as a one-character String.
+func (s *String) Iter() Iter {
+	i := 0
+	return IterFunc(func() (Obj, bool) {
+		if i >= len(s.Value) {
+			return nil, false
+		}
+		_, size := utf8.DecodeRuneInString(s.Value[i:])
+		i += size
+		return &String{Value: s.Value[i-size : i]}, true
+	})
+}
+
+// Iter walks the keys of the hash in insertion order.
+func (h *Hash) Iter() Iter {
+	i := 0
+	return IterFunc(func() (Obj, bool) {
+		if i >= len(h.Keys) {
+			return nil, false
+		}
+		i++
+		return h.Pairs[h.Keys[i-1]].Key, true
+	})
+}
+
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+func (r *Range) Type() ObjType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+func (r *Range) Len() int64 {
+	var n int64
+	if r.Step > 0 && r.Start < r.End {
+		n = (r.End - r.Start + r.Step - 1) / r.Step
+	} else if r.Step < 0 && r.Start > r.End {
+		n = (r.Start - r.End - r.Step - 1) / -r.Step
+	}
+	return n
+}
+
+func (r *Range) Iter() Iter {
+	i := r.Start
+	return IterFunc(func() (Obj, bool) {
+		if (r.Step > 0 && i >= r.End) || (r.Step < 0 && i <= r.End) {
+			return nil, false
+		}
+		i += r.Step
+		return &Integer{Value: i - r.Step}, true
+	})
+}
+
+// Iterator is a lazy, single-pass sequence. Its combinators wrap the
+// underlying Iter and pull one element at a time, so chaining them never
+// builds intermediate arrays.
+type Iterator struct {
+	it   Iter
+	done bool
+}
+
+func NewIterator(it Iter) *Iterator {
+	return &Iterator{it: it}
+}
+
+func (i *Iterator) Type() ObjType   { return ITERATOR_OBJ }
+func (i *Iterator) Inspect() string { return "iterator" }
+func (i *Iterator) Iter() Iter      { return i }
+
+func (i *Iterator) Next() (Obj, bool) {
+	if i.done {
+		return nil, false
+	}
+
+	o, ok := i.it.Next()
+	if !ok {
+		i.done = true
+	}
+	return o, ok
+}
+
+// Close ends the iterator early. An Iter with a Close method of its own,
+// like that of a generator, is closed instead, releasing what it holds and
+// deciding what a later Next returns.
+func (i *Iterator) Close() {
+	if c, ok := i.it.(interface{ Close() }); ok {
+		c.Close()
+		return
+	}
+	i.done = true
+}
+
+func (i *Iterator) Map(fn func(Obj) Obj) *Iterator {
+	return NewIterator(IterFunc(func() (Obj, bool) {
+		o, ok := i.Next()
+		if !ok {
+			return nil, false
+		}
+		if o.Type() == ERROR_OBJ {
+			return o, true
+		}
+		return fn(o), true
+	}))
+}
+
+func (i *Iterator) Filter(keep func(Obj) (bool, *Error)) *Iterator {
+	return NewIterator(IterFunc(func() (Obj, bool) {
+		for {
+			o, ok := i.Next()
+			if !ok {
+				return nil, false
+			}
+			if o.Type() == ERROR_OBJ {
+				return o, true
+			}
+			k, err := keep(o)
+			if err != nil {
+				return err, true
+			}
+			if k {
+				return o, true
+			}
+		}
+	}))
+}
+
+func (i *Iterator) Take(n int64) *Iterator {
+	return NewIterator(IterFunc(func() (Obj, bool) {
+		if n <= 0 {
+			return nil, false
+		}
+		n--
+		return i.Next()
+	}))
+}
+
+func (i *Iterator) Skip(n int64) *Iterator {
+	return NewIterator(IterFunc(func() (Obj, bool) {
+		for ; n > 0; n-- {
+			o, ok := i.Next()
+			if !ok {
+				return nil, false
+			}
+			if o.Type() == ERROR_OBJ {
+				return o, true
+			}
+		}
+		return i.Next()
+	}))
+}
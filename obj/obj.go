@@ -3,6 +3,7 @@ package obj
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"strings"
 
 	"github.com/mdaisuke/monk/ast"
@@ -21,6 +22,18 @@ const (
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
+	MODULE_OBJ       = "MODULE"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	ITERATOR_OBJ     = "ITERATOR"
+	RANGE_OBJ        = "RANGE"
+	STRUCT_TYPE_OBJ  = "STRUCT_TYPE"
+	STRUCT_OBJ       = "STRUCT"
+	ENUM_OBJ         = "ENUM"
+	VARIANT_TYPE_OBJ = "VARIANT_TYPE"
+	VARIANT_OBJ      = "VARIANT"
+	CHANNEL_OBJ      = "CHANNEL"
+	TASK_OBJ         = "TASK"
 )
 
 type Obj interface {
@@ -54,17 +67,58 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjType   { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
+// ErrorKind tells apart errors a host may want to handle specially from
+// ordinary runtime errors, whose Kind is empty.
+type ErrorKind string
+
+const (
+	STEP_LIMIT  ErrorKind = "STEP_LIMIT"
+	TIME_LIMIT  ErrorKind = "TIME_LIMIT"
+	DEPTH_LIMIT ErrorKind = "DEPTH_LIMIT"
+	ALLOC_LIMIT ErrorKind = "ALLOC_LIMIT"
+	FORBIDDEN   ErrorKind = "FORBIDDEN"
+	CANCELED    ErrorKind = "CANCELED"
+	EXIT        ErrorKind = "EXIT"
+)
+
+// Error carries, in Trace, the names of the functions it propagated out
+// of, innermost first. Inspect folds runs of the same name, as left by
+// recursion.
 type Error struct {
+	Kind    ErrorKind
 	Message string
+	Trace   []string
+	// Code is the status passed to exit, for errors of kind EXIT.
+	Code int
 }
 
-func (e *Error) Type() ObjType   { return ERROR_OBJ }
-func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+func (e *Error) Type() ObjType { return ERROR_OBJ }
+func (e *Error) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("ERROR: " + e.Message)
+	for i := 0; i < len(e.Trace); {
+		n := 1
+		for i+n < len(e.Trace) && e.Trace[i+n] == e.Trace[i] {
+			n++
+		}
+
+		out.WriteString("\n    at " + e.Trace[i])
+		if n > 1 {
+			out.WriteString(fmt.Sprintf(" (%d times)", n))
+		}
+		i += n
+	}
+
+	return out.String()
+}
 
 type Function struct {
-	Params []*ast.Identifier
-	Body   *ast.BlockStmt
-	Env    *Env
+	Name      string // empty for anonymous functions
+	Params    []*ast.Identifier
+	Body      *ast.BlockStmt
+	Env       *Env
+	Generator bool
 }
 
 func (f *Function) Type() ObjType { return FUNCTION_OBJ }
@@ -77,6 +131,9 @@ func (f *Function) Inspect() string {
 	}
 
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -86,6 +143,18 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// Composed is the function produced by `first >> then`: it calls first
+// with its arguments and then with the result.
+type Composed struct {
+	First Obj
+	Then  Obj
+}
+
+func (c *Composed) Type() ObjType { return FUNCTION_OBJ }
+func (c *Composed) Inspect() string {
+	return "(" + c.First.Inspect() + " >> " + c.Then.Inspect() + ")"
+}
+
 type String struct {
 	Value string
 }
@@ -99,3 +168,271 @@ type Builtin struct {
 
 func (b *Builtin) Type() ObjType   { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string { return "builtin function" }
+
+type Module struct {
+	Name string
+	Path string
+	Env  *Env
+}
+
+func (m *Module) Type() ObjType   { return MODULE_OBJ }
+func (m *Module) Inspect() string { return "module " + m.Name }
+
+type Array struct {
+	Elems []Obj
+}
+
+func (a *Array) Type() ObjType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elems := []string{}
+	for _, e := range a.Elems {
+		elems = append(elems, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elems, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+type HashKey struct {
+	Type  ObjType
+	Value uint64
+}
+
+type Hashable interface {
+	Obj
+	HashKey() HashKey
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+
+	if b.Value {
+		value = 1
+	} else {
+		value = 0
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+	Key   Obj
+	Value Obj
+}
+
+// Hash keeps its pairs in insertion order so that printing and iterating
+// a hash is deterministic.
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+func (h *Hash) Get(key Hashable) (Obj, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	if !ok || !Equal(pair.Key, key) {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+func (h *Hash) Set(key Hashable, value Obj) {
+	hk := key.HashKey()
+	if _, ok := h.Pairs[hk]; !ok {
+		h.Keys = append(h.Keys, hk)
+	}
+	h.Pairs[hk] = HashPair{Key: key, Value: value}
+}
+
+func (h *Hash) Type() ObjType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, hk := range h.Keys {
+		pair := h.Pairs[hk]
+		pairs = append(pairs, fmt.Sprintf("%s: %s",
+			pair.Key.Inspect(), pair.Value.Inspect()))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// StructType is the value bound by a struct declaration. Calling it
+// constructs a Struct with one argument per field. Methods are added by
+// impl blocks and receive the struct as their first argument.
+type StructType struct {
+	Name    string
+	Fields  []string
+	Methods map[string]Obj
+}
+
+func (st *StructType) Type() ObjType { return STRUCT_TYPE_OBJ }
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+func (st *StructType) FieldIndex(name string) (int, bool) {
+	for i, f := range st.Fields {
+		if f == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Struct holds one value per field of its StructType, in declaration
+// order. Structs are never modified in place; With returns a copy.
+type Struct struct {
+	StructType *StructType
+	Values     []Obj
+}
+
+func (s *Struct) Type() ObjType { return STRUCT_OBJ }
+func (s *Struct) Inspect() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for i, f := range s.StructType.Fields {
+		fields = append(fields, f+": "+s.Values[i].Inspect())
+	}
+
+	out.WriteString(s.StructType.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (s *Struct) Get(name string) (Obj, bool) {
+	i, ok := s.StructType.FieldIndex(name)
+	if !ok {
+		return nil, false
+	}
+	return s.Values[i], true
+}
+
+// EnumType is the value bound by an enum declaration. Its variants are
+// also reachable as selectors, e.g. Shape.Circle.
+type EnumType struct {
+	Name     string
+	Variants []*VariantType
+	Methods  map[string]Obj
+}
+
+func (et *EnumType) Type() ObjType { return ENUM_OBJ }
+func (et *EnumType) Inspect() string {
+	variants := []string{}
+	for _, v := range et.Variants {
+		variants = append(variants, v.signature())
+	}
+	return "enum " + et.Name + " { " + strings.Join(variants, ", ") + " }"
+}
+
+func (et *EnumType) Variant(name string) (*VariantType, bool) {
+	for _, v := range et.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// VariantType constructs the values of one enum variant. A variant
+// declared without parentheses has no constructor, only the single value
+// Unit.
+type VariantType struct {
+	Enum   *EnumType
+	Name   string
+	Fields []string
+	Unit   *Variant
+}
+
+func (vt *VariantType) Type() ObjType   { return VARIANT_TYPE_OBJ }
+func (vt *VariantType) Inspect() string { return vt.Enum.Name + "." + vt.signature() }
+
+func (vt *VariantType) signature() string {
+	if vt.Unit != nil {
+		return vt.Name
+	}
+	return vt.Name + "(" + strings.Join(vt.Fields, ", ") + ")"
+}
+
+// Value returns what the variant's name is bound to: the constructor, or
+// the single value of a variant without fields.
+func (vt *VariantType) Value() Obj {
+	if vt.Unit != nil {
+		return vt.Unit
+	}
+	return vt
+}
+
+type Variant struct {
+	VariantType *VariantType
+	Values      []Obj
+}
+
+func (v *Variant) Type() ObjType { return VARIANT_OBJ }
+func (v *Variant) Inspect() string {
+	if v.VariantType.Unit != nil {
+		return v.VariantType.Name
+	}
+
+	values := []string{}
+	for _, val := range v.Values {
+		values = append(values, val.Inspect())
+	}
+	return v.VariantType.Name + "(" + strings.Join(values, ", ") + ")"
+}
+
+func (v *Variant) Get(name string) (Obj, bool) {
+	for i, f := range v.VariantType.Fields {
+		if f == name {
+			return v.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// Channel passes values between tasks. The evaluator keeps its buffer and
+// the tasks blocked on it in State, under a lock of its own.
+type Channel struct {
+	Cap   int
+	State interface{}
+}
+
+func (c *Channel) Type() ObjType   { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string { return fmt.Sprintf("channel(%d)", c.Cap) }
+
+// Task is a call running concurrently, as started by spawn. The evaluator
+// keeps its outcome in State.
+type Task struct {
+	State interface{}
+}
+
+func (t *Task) Type() ObjType   { return TASK_OBJ }
+func (t *Task) Inspect() string { return "task" }
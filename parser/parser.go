@@ -12,8 +12,11 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	NULLISH
 	EQUALS
 	LESSGREATER
+	PIPE    // a + b |> f(c) == d is ((a + b) |> f(c)) == d
+	COMPOSE // f >> g(a) composes f with the result of calling g
 	SUM
 	PRODUCT
 	PREFIX
@@ -22,16 +25,24 @@ const (
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.EQ:          EQUALS,
+	token.NOT_EQ:      EQUALS,
+	token.LT:          LESSGREATER,
+	token.GT:          LESSGREATER,
+	token.PLUS:        SUM,
+	token.MINUS:       SUM,
+	token.SLASH:       PRODUCT,
+	token.ASTERISK:    PRODUCT,
+	token.LPAREN:      CALL,
+	token.LBRACKET:    INDEX,
+	token.DOT:         INDEX,
+	token.WITH:        INDEX,
+	token.IS:          EQUALS,
+	token.PIPE:        PIPE,
+	token.COMPOSE:     COMPOSE,
+	token.NULLISH:     NULLISH,
+	token.OPT_DOT:     INDEX,
+	token.OPT_BRACKET: INDEX,
 }
 
 type (
@@ -48,6 +59,10 @@ type Parser struct {
 
 	nuds map[token.TokenType]nud
 	leds map[token.TokenType]led
+
+	// funcs holds the function literals being parsed, innermost last, so
+	// that yield can mark its enclosing function as a generator.
+	funcs []*ast.FunctionLiteral
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -61,6 +76,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerNud(token.INT, p.parseIntegerLiteral)
 	p.registerNud(token.BANG, p.parsePrefixExp)
 	p.registerNud(token.MINUS, p.parsePrefixExp)
+	p.registerNud(token.NULL, p.parseNullLiteral)
 	p.registerNud(token.TRUE, p.parseBoolean)
 	p.registerNud(token.FALSE, p.parseBoolean)
 	p.registerNud(token.LPAREN, p.parseGroupedExp)
@@ -68,6 +84,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerNud(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerNud(token.STRING, p.parseStringLiteral)
 	p.registerNud(token.LBRACKET, p.parseArrayLiteral)
+	p.registerNud(token.LBRACE, p.parseHashLiteral)
+	p.registerNud(token.YIELD, p.parseYieldExp)
+	p.registerNud(token.MATCH, p.parseMatchExp)
+	p.registerNud(token.SPAWN, p.parseSpawnExp)
+	p.registerNud(token.SELECT, p.parseSelectExp)
 	p.leds = make(map[token.TokenType]led)
 	p.registerLed(token.PLUS, p.parseInfixExp)
 	p.registerLed(token.MINUS, p.parseInfixExp)
@@ -79,6 +100,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerLed(token.GT, p.parseInfixExp)
 	p.registerLed(token.LPAREN, p.parseCallExp)
 	p.registerLed(token.LBRACKET, p.parseIndexExp)
+	p.registerLed(token.DOT, p.parseSelectorExp)
+	p.registerLed(token.OPT_DOT, p.parseSelectorExp)
+	p.registerLed(token.OPT_BRACKET, p.parseIndexExp)
+	p.registerLed(token.NULLISH, p.parseInfixExp)
+	p.registerLed(token.WITH, p.parseWithExp)
+	p.registerLed(token.IS, p.parseInfixExp)
+	p.registerLed(token.PIPE, p.parsePipeExp)
+	p.registerLed(token.COMPOSE, p.parseInfixExp)
 
 	p.nextToken()
 	p.nextToken()
@@ -122,6 +151,23 @@ func (p *Parser) parseStmt() ast.Stmt {
 		return p.parseLetStmt()
 	case token.RETURN:
 		return p.parseReturnStmt()
+	case token.IMPORT:
+		return p.parseImportStmt()
+	case token.EXPORT:
+		return p.parseExportStmt()
+	case token.FOR:
+		return p.parseForStmt()
+	case token.STRUCT:
+		return p.parseStructStmt()
+	case token.IMPL:
+		return p.parseImplStmt()
+	case token.ENUM:
+		return p.parseEnumStmt()
+	case token.FUNCTION:
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionStmt()
+		}
+		return p.parseExpStmt()
 	default:
 		return p.parseExpStmt()
 	}
@@ -144,7 +190,32 @@ func (p *Parser) parseLetStmt() *ast.LetStmt {
 
 	stmt.Value = p.parseExp(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = stmt.Name.Value
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseFunctionStmt() *ast.FunctionStmt {
+	stmt := &ast.FunctionStmt{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	lit, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok {
+		return nil
+	}
+	lit.Token = stmt.Token
+	lit.Name = stmt.Name.Value
+	stmt.Function = lit
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -176,7 +247,7 @@ func (p *Parser) parseReturnStmt() *ast.ReturnStmt {
 
 	stmt.ReturnValue = p.parseExp(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -290,6 +361,19 @@ func (p *Parser) parseInfixExp(left ast.Exp) ast.Exp {
 	return exp
 }
 
+func (p *Parser) parsePipeExp(left ast.Exp) ast.Exp {
+	exp := &ast.PipeExp{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Right = p.parseExp(PIPE)
+
+	return exp
+}
+
+func (p *Parser) parseNullLiteral() ast.Exp {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseBoolean() ast.Exp {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -369,7 +453,9 @@ func (p *Parser) parseFunctionLiteral() ast.Exp {
 		return nil
 	}
 
+	p.funcs = append(p.funcs, lit)
 	lit.Body = p.parseBlockStmt()
+	p.funcs = p.funcs[:len(p.funcs)-1]
 
 	return lit
 }
@@ -468,7 +554,11 @@ func (p *Parser) parseExpList(end token.TokenType) []ast.Exp {
 }
 
 func (p *Parser) parseIndexExp(left ast.Exp) ast.Exp {
-	exp := &ast.IndexExp{Token: p.curToken, Left: left}
+	exp := &ast.IndexExp{
+		Token:    p.curToken,
+		Left:     left,
+		Optional: p.curTokenIs(token.OPT_BRACKET),
+	}
 
 	p.nextToken()
 	exp.Index = p.parseExp(LOWEST)
@@ -479,3 +569,512 @@ func (p *Parser) parseIndexExp(left ast.Exp) ast.Exp {
 
 	return exp
 }
+
+func (p *Parser) parseImportStmt() *ast.ImportStmt {
+	stmt := &ast.ImportStmt{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExportStmt() *ast.ExportStmt {
+	stmt := &ast.ExportStmt{Token: p.curToken}
+
+	p.nextToken()
+
+	switch p.curToken.Type {
+	case token.LET:
+		decl := p.parseLetStmt()
+		if decl == nil {
+			return nil
+		}
+		stmt.Name = decl.Name
+		stmt.Decl = decl
+	case token.STRUCT:
+		decl := p.parseStructStmt()
+		if decl == nil {
+			return nil
+		}
+		stmt.Name = decl.Name
+		stmt.Decl = decl
+	case token.ENUM:
+		decl := p.parseEnumStmt()
+		if decl == nil {
+			return nil
+		}
+		stmt.Name = decl.Name
+		stmt.Decl = decl
+	case token.FUNCTION:
+		if !p.peekTokenIs(token.IDENT) {
+			p.peekError(token.IDENT)
+			return nil
+		}
+		decl := p.parseFunctionStmt()
+		if decl == nil {
+			return nil
+		}
+		stmt.Name = decl.Name
+		stmt.Decl = decl
+	default:
+		msg := fmt.Sprintf("expected declaration after export, got=%s instead",
+			p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseSelectorExp(left ast.Exp) ast.Exp {
+	exp := &ast.SelectorExp{
+		Token:    p.curToken,
+		Left:     left,
+		Optional: p.curTokenIs(token.OPT_DOT),
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	exp.Sel = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+func (p *Parser) parseHashLiteral() ast.Exp {
+	hash := &ast.HashLiteral{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExp(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExp(LOWEST)
+
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+func (p *Parser) parseForStmt() *ast.ForStmt {
+	stmt := &ast.ForStmt{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Var = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExp(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStmt()
+
+	return stmt
+}
+
+func (p *Parser) parseYieldExp() ast.Exp {
+	exp := &ast.YieldExp{Token: p.curToken}
+
+	if len(p.funcs) == 0 {
+		p.errors = append(p.errors, "yield outside of function")
+		return nil
+	}
+	p.funcs[len(p.funcs)-1].Generator = true
+
+	p.nextToken()
+	exp.Value = p.parseExp(LOWEST)
+
+	return exp
+}
+
+func (p *Parser) parseStructStmt() *ast.StructStmt {
+	stmt := &ast.StructStmt{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Fields = p.parseFieldNames(token.RBRACE)
+	if stmt.Fields == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseFieldNames parses a comma separated list of distinct identifiers
+// ending with the end token.
+func (p *Parser) parseFieldNames(end token.TokenType) []*ast.Identifier {
+	fields := []*ast.Identifier{}
+	seen := map[string]bool{}
+
+	for !p.peekTokenIs(end) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		if seen[p.curToken.Literal] {
+			msg := fmt.Sprintf("duplicate field %s", p.curToken.Literal)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		seen[p.curToken.Literal] = true
+
+		fields = append(fields, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(end) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return fields
+}
+
+func (p *Parser) parseWithExp(left ast.Exp) ast.Exp {
+	exp := &ast.WithExp{Token: p.curToken, Left: left}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		exp.Names = append(exp.Names, name)
+		exp.Values = append(exp.Values, p.parseExp(LOWEST))
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseImplStmt() *ast.ImplStmt {
+	stmt := &ast.ImplStmt{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStmt()
+
+	for _, s := range stmt.Body.Stmts {
+		switch s.(type) {
+		case *ast.LetStmt, *ast.FunctionStmt:
+		default:
+			msg := fmt.Sprintf("expected let or fn declaration in impl block, got %q", s.String())
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseEnumStmt() *ast.EnumStmt {
+	stmt := &ast.EnumStmt{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := map[string]bool{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		if seen[p.curToken.Literal] {
+			msg := fmt.Sprintf("duplicate variant %s", p.curToken.Literal)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		seen[p.curToken.Literal] = true
+
+		variant := &ast.EnumVariant{
+			Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+		}
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			variant.Fields = p.parseFieldNames(token.RPAREN)
+			if variant.Fields == nil {
+				return nil
+			}
+		}
+
+		stmt.Variants = append(stmt.Variants, variant)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseMatchExp() ast.Exp {
+	exp := &ast.MatchExp{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	exp.Subject = p.parseExp(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	arm := &ast.MatchArm{
+		Pattern: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+	}
+
+	for p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		arm.Pattern = p.parseSelectorExp(arm.Pattern)
+		if arm.Pattern == nil {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		arm.Bindings = []*ast.Identifier{}
+
+		for !p.peekTokenIs(token.RPAREN) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			arm.Bindings = append(arm.Bindings,
+				&ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+			if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	arm.Body = p.parseArmBody()
+	return arm
+}
+
+// parseArmBody parses what follows the => of a match arm or select case:
+// a block, or a single expression.
+func (p *Parser) parseArmBody() *ast.BlockStmt {
+	p.nextToken()
+
+	if p.curTokenIs(token.LBRACE) {
+		return p.parseBlockStmt()
+	}
+
+	stmt := &ast.ExpStmt{Token: p.curToken, Exp: p.parseExp(LOWEST)}
+	return &ast.BlockStmt{Token: stmt.Token, Stmts: []ast.Stmt{stmt}}
+}
+
+func (p *Parser) parseSpawnExp() ast.Exp {
+	exp := &ast.SpawnExp{Token: p.curToken}
+
+	p.nextToken()
+	call, ok := p.parseExp(PREFIX).(*ast.CallExp)
+	if !ok {
+		p.errors = append(p.errors, "spawn needs a call")
+		return nil
+	}
+	exp.Call = call
+
+	return exp
+}
+
+func (p *Parser) parseSelectExp() ast.Exp {
+	exp := &ast.SelectExp{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		c := p.parseSelectCase()
+		if c == nil {
+			return nil
+		}
+		exp.Cases = append(exp.Cases, c)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseSelectCase() *ast.SelectCase {
+	p.nextToken()
+	c := &ast.SelectCase{}
+
+	if !p.curTokenIs(token.IDENT) || p.curToken.Literal != "_" {
+		call, ok := p.parseExp(LOWEST).(*ast.CallExp)
+		var op *ast.Identifier
+		if ok {
+			op, _ = call.Function.(*ast.Identifier)
+		}
+		if op == nil || op.Value != "recv" && op.Value != "send" {
+			p.errors = append(p.errors, "select case must be recv(ch), send(ch, value) or _")
+			return nil
+		}
+		c.Comm = call
+
+		if op.Value == "recv" && p.peekTokenIs(token.AS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			c.Binding = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		}
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	c.Body = p.parseArmBody()
+	return c
+}
@@ -406,6 +406,62 @@ func TestOpPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"-m.x * m.f(a)[0]",
+			"((-m.x) * (m.f(a)[0]))",
+		},
+		{
+			"p with {x: 1 + 2} == q",
+			"((p with {x: (1 + 2)}) == q)",
+		},
+		{
+			"s is Shape == true",
+			"((s is Shape) == true)",
+		},
+		{
+			"a + 1 is Int",
+			"((a + 1) is Int)",
+		},
+		{
+			"xs |> f(a) |> g",
+			"((xs |> f(a)) |> g)",
+		},
+		{
+			"a + b |> f(c) == d",
+			"(((a + b) |> f(c)) == d)",
+		},
+		{
+			"xs |> f >> g(a)",
+			"(xs |> (f >> g(a)))",
+		},
+		{
+			"f >> g >> h",
+			"((f >> g) >> h)",
+		},
+		{
+			"xs |> m.f(a)[0]",
+			"(xs |> (m.f(a)[0]))",
+		},
+		{
+			"a?.b?.c",
+			"a?.b?.c",
+		},
+		{
+			"a?[k]?.b[0]",
+			"((a?[k])?.b[0])",
+		},
+		{
+			"a?.b ?? c + 1 == d",
+			"(a?.b ?? ((c + 1) == d))",
+		},
+		{
+			"a ?? b ?? null",
+			"((a ?? b) ?? null)",
+		},
+		{
+			"x |> f < y |> g",
+			"((x |> f) < (y |> g))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -838,3 +894,620 @@ func TestParsingIndexExps(t *testing.T) {
 	}
 
 }
+
+func TestImportStmts(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedPath  string
+		expectedAlias string
+	}{
+		{`import "lib/strings.mk" as s;`, "lib/strings.mk", "s"},
+		{`import "util.mk"`, "util.mk", ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Stmts) != 1 {
+			t.Fatalf("len(program.Stmts) is not 1. got=%d",
+				len(program.Stmts))
+		}
+
+		stmt, ok := program.Stmts[0].(*ast.ImportStmt)
+		if !ok {
+			t.Fatalf("program.Stmts[0] is not ast.ImportStmt. got=%T",
+				program.Stmts[0])
+		}
+
+		if stmt.Path.Value != tt.expectedPath {
+			t.Errorf("stmt.Path.Value is not %q. got=%q",
+				tt.expectedPath, stmt.Path.Value)
+		}
+
+		if tt.expectedAlias == "" {
+			if stmt.Alias != nil {
+				t.Errorf("stmt.Alias is not nil. got=%+v", stmt.Alias)
+			}
+			continue
+		}
+
+		testIdentifier(t, stmt.Alias, tt.expectedAlias)
+	}
+}
+
+func TestExportStmt(t *testing.T) {
+	input := "export let x = 5\nexport let y = x;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Stmts) != 2 {
+		t.Fatalf("len(program.Stmts) is not 2. got=%d",
+			len(program.Stmts))
+	}
+
+	for i, name := range []string{"x", "y"} {
+		stmt, ok := program.Stmts[i].(*ast.ExportStmt)
+		if !ok {
+			t.Fatalf("program.Stmts[%d] is not ast.ExportStmt. got=%T",
+				i, program.Stmts[i])
+		}
+
+		if stmt.Name.Value != name {
+			t.Errorf("stmt.Name.Value is not %q. got=%q", name, stmt.Name.Value)
+		}
+
+		if !testLetStmt(t, stmt.Decl, name) {
+			return
+		}
+	}
+}
+
+func TestParsingSelectorExps(t *testing.T) {
+	input := "s.upper"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Stmts[0].(*ast.ExpStmt)
+	sel, ok := stmt.Exp.(*ast.SelectorExp)
+	if !ok {
+		t.Fatalf("stmt.Exp is not ast.SelectorExp. got=%T", stmt.Exp)
+	}
+
+	if !testIdentifier(t, sel.Left, "s") {
+		return
+	}
+
+	testIdentifier(t, sel.Sel, "upper")
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	input := `{"one": 1, "two": 2, "three": 3}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Stmts[0].(*ast.ExpStmt)
+	hash, ok := stmt.Exp.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Exp)
+	}
+
+	expected := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
+	}
+
+	if len(hash.Keys) != len(expected) {
+		t.Fatalf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+	}
+
+	for i, e := range expected {
+		literal, ok := hash.Keys[i].(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("key is not ast.StringLiteral. got=%T", hash.Keys[i])
+			continue
+		}
+		if literal.Value != e.key {
+			t.Errorf("key is not %q. got=%q", e.key, literal.Value)
+		}
+		testIntegerLiteral(t, hash.Values[i], e.value)
+	}
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+	input := "{}"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Stmts[0].(*ast.ExpStmt)
+	hash, ok := stmt.Exp.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Exp)
+	}
+
+	if len(hash.Keys) != 0 {
+		t.Errorf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+	}
+}
+
+func TestForStmt(t *testing.T) {
+	input := `for (x in xs) { x + 1 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Stmts) != 1 {
+		t.Fatalf("len(program.Stmts) is not 1. got=%d",
+			len(program.Stmts))
+	}
+
+	stmt, ok := program.Stmts[0].(*ast.ForStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not ast.ForStmt. got=%T",
+			program.Stmts[0])
+	}
+
+	if !testIdentifier(t, stmt.Var, "x") {
+		return
+	}
+
+	if !testIdentifier(t, stmt.Iterable, "xs") {
+		return
+	}
+
+	if len(stmt.Body.Stmts) != 1 {
+		t.Fatalf("len(stmt.Body.Stmts) is not 1. got=%d",
+			len(stmt.Body.Stmts))
+	}
+
+	body := stmt.Body.Stmts[0].(*ast.ExpStmt)
+	testInfixExp(t, body.Exp, "x", "+", 1)
+}
+
+func TestYieldMarksGenerator(t *testing.T) {
+	input := `fn() { fn() { yield 1 }; 2 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	outer := program.Stmts[0].(*ast.ExpStmt).Exp.(*ast.FunctionLiteral)
+	if outer.Generator {
+		t.Errorf("outer function is marked as a generator")
+	}
+
+	inner := outer.Body.Stmts[0].(*ast.ExpStmt).Exp.(*ast.FunctionLiteral)
+	if !inner.Generator {
+		t.Errorf("inner function is not marked as a generator")
+	}
+
+	yield, ok := inner.Body.Stmts[0].(*ast.ExpStmt).Exp.(*ast.YieldExp)
+	if !ok {
+		t.Fatalf("inner body is not ast.YieldExp. got=%T",
+			inner.Body.Stmts[0].(*ast.ExpStmt).Exp)
+	}
+	testIntegerLiteral(t, yield.Value, 1)
+}
+
+func TestYieldOutsideFunction(t *testing.T) {
+	l := lexer.New("yield 1")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "yield outside of function" {
+		t.Errorf("expected yield outside of function error. got=%q", errors)
+	}
+}
+
+func TestStructStmt(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedFields []string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}},
+		{"struct Empty {};", "Empty", []string{}},
+		{"export struct Pair { first, second }", "Pair", []string{"first", "second"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Stmts) != 1 {
+			t.Fatalf("len(program.Stmts) is not 1. got=%d",
+				len(program.Stmts))
+		}
+
+		stmt := program.Stmts[0]
+		if export, ok := stmt.(*ast.ExportStmt); ok {
+			stmt = export.Decl
+		}
+
+		ss, ok := stmt.(*ast.StructStmt)
+		if !ok {
+			t.Fatalf("stmt is not ast.StructStmt. got=%T", stmt)
+		}
+
+		testIdentifier(t, ss.Name, tt.expectedName)
+
+		if len(ss.Fields) != len(tt.expectedFields) {
+			t.Fatalf("len(ss.Fields) is not %d. got=%d",
+				len(tt.expectedFields), len(ss.Fields))
+		}
+
+		for i, f := range tt.expectedFields {
+			testIdentifier(t, ss.Fields[i], f)
+		}
+	}
+}
+
+func TestStructStmtErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"struct Point { x, x }", "duplicate field x"},
+		{"struct Point { x y }", "expected next token to be ,, got=IDENT instead"},
+		{"export 5", "expected declaration after export, got=INT instead"},
+		{"impl P { 5 }", "expected let or fn declaration in impl block, got \"5\""},
+		{"enum Shape { Empty, Empty }", "duplicate variant Empty"},
+		{"enum Shape { Circle(r, r) }", "duplicate field r"},
+		{"match (s) { 5 => 1 }", "expected next token to be IDENT, got=INT instead"},
+		{"match (s) { Circle(r) 1 }", "expected next token to be =>, got=INT instead"},
+		{"match (s) { Shape.5 => 1 }", "expected next token to be IDENT, got=INT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expectedError {
+			t.Errorf("expected error %q. got=%q", tt.expectedError, errors)
+		}
+	}
+}
+
+func TestWithExp(t *testing.T) {
+	input := "p with {x: 3, y: y + 1}"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Stmts[0].(*ast.ExpStmt)
+	exp, ok := stmt.Exp.(*ast.WithExp)
+	if !ok {
+		t.Fatalf("stmt.Exp is not ast.WithExp. got=%T", stmt.Exp)
+	}
+
+	testIdentifier(t, exp.Left, "p")
+
+	if len(exp.Names) != 2 {
+		t.Fatalf("len(exp.Names) is not 2. got=%d", len(exp.Names))
+	}
+
+	testIdentifier(t, exp.Names[0], "x")
+	testIntegerLiteral(t, exp.Values[0], 3)
+	testIdentifier(t, exp.Names[1], "y")
+	testInfixExp(t, exp.Values[1], "y", "+", 1)
+}
+
+func TestImplStmt(t *testing.T) {
+	input := `impl Point { let norm = fn(self) { self.x }; let zero = 0; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Stmts[0].(*ast.ImplStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not ast.ImplStmt. got=%T", program.Stmts[0])
+	}
+
+	testIdentifier(t, stmt.Name, "Point")
+
+	if len(stmt.Body.Stmts) != 2 {
+		t.Fatalf("len(stmt.Body.Stmts) is not 2. got=%d", len(stmt.Body.Stmts))
+	}
+
+	testLetStmt(t, stmt.Body.Stmts[0], "norm")
+	testLetStmt(t, stmt.Body.Stmts[1], "zero")
+}
+
+func TestEnumStmt(t *testing.T) {
+	input := `enum Shape { Circle(r), Rect(w, h), Empty }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Stmts[0].(*ast.EnumStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not ast.EnumStmt. got=%T", program.Stmts[0])
+	}
+
+	testIdentifier(t, stmt.Name, "Shape")
+
+	expected := []struct {
+		name   string
+		fields []string
+	}{
+		{"Circle", []string{"r"}},
+		{"Rect", []string{"w", "h"}},
+		{"Empty", nil},
+	}
+
+	if len(stmt.Variants) != len(expected) {
+		t.Fatalf("len(stmt.Variants) is not %d. got=%d",
+			len(expected), len(stmt.Variants))
+	}
+
+	for i, tt := range expected {
+		v := stmt.Variants[i]
+		testIdentifier(t, v.Name, tt.name)
+
+		if (v.Fields == nil) != (tt.fields == nil) || len(v.Fields) != len(tt.fields) {
+			t.Fatalf("wrong fields for %s. got=%v", tt.name, v.Fields)
+		}
+		for j, f := range tt.fields {
+			testIdentifier(t, v.Fields[j], f)
+		}
+	}
+
+	if stmt.String() != input {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestMatchExp(t *testing.T) {
+	input := `match (s) { Circle(r) => r * r, Shape.Rect(w, _) => { w } shapes.Shape.Empty => 0 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Stmts[0].(*ast.ExpStmt)
+	exp, ok := stmt.Exp.(*ast.MatchExp)
+	if !ok {
+		t.Fatalf("stmt.Exp is not ast.MatchExp. got=%T", stmt.Exp)
+	}
+
+	testIdentifier(t, exp.Subject, "s")
+
+	expected := []struct {
+		pattern  string
+		bindings []string
+		body     string
+	}{
+		{"Circle", []string{"r"}, "(r * r)"},
+		{"Shape.Rect", []string{"w", "_"}, "w"},
+		{"shapes.Shape.Empty", nil, "0"},
+	}
+
+	if len(exp.Arms) != len(expected) {
+		t.Fatalf("len(exp.Arms) is not %d. got=%d", len(expected), len(exp.Arms))
+	}
+
+	for i, tt := range expected {
+		arm := exp.Arms[i]
+		if arm.Pattern.String() != tt.pattern {
+			t.Errorf("wrong pattern. expected=%q, got=%q", tt.pattern, arm.Pattern.String())
+		}
+
+		if (arm.Bindings == nil) != (tt.bindings == nil) || len(arm.Bindings) != len(tt.bindings) {
+			t.Fatalf("wrong bindings for %s. got=%v", tt.pattern, arm.Bindings)
+		}
+		for j, b := range tt.bindings {
+			testIdentifier(t, arm.Bindings[j], b)
+		}
+
+		if arm.Body.String() != tt.body {
+			t.Errorf("wrong body for %s. expected=%q, got=%q",
+				tt.pattern, tt.body, arm.Body.String())
+		}
+	}
+}
+
+func TestFunctionStmt(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedParams []string
+		expectedString string
+	}{
+		{"fn add(x, y) { x + y }", "add", []string{"x", "y"}, "fn add(x, y)(x + y)"},
+		{"fn nop() {}", "nop", []string{}, "fn nop()"},
+		{"fn one() { 1 };", "one", []string{}, "fn one()1"},
+		{"export fn id(x) { x }", "id", []string{"x"}, "fn id(x)x"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Stmts) != 1 {
+			t.Fatalf("len(program.Stmts) is not 1. got=%d", len(program.Stmts))
+		}
+
+		stmt := program.Stmts[0]
+		if export, ok := stmt.(*ast.ExportStmt); ok {
+			testIdentifier(t, export.Name, tt.expectedName)
+			stmt = export.Decl
+		}
+
+		fs, ok := stmt.(*ast.FunctionStmt)
+		if !ok {
+			t.Fatalf("stmt is not ast.FunctionStmt. got=%T", stmt)
+		}
+
+		testIdentifier(t, fs.Name, tt.expectedName)
+
+		if fs.Function.Name != tt.expectedName {
+			t.Errorf("fs.Function.Name is not %q. got=%q",
+				tt.expectedName, fs.Function.Name)
+		}
+
+		if len(fs.Function.Params) != len(tt.expectedParams) {
+			t.Fatalf("len(fs.Function.Params) is not %d. got=%d",
+				len(tt.expectedParams), len(fs.Function.Params))
+		}
+		for i, param := range tt.expectedParams {
+			testLiteralExp(t, fs.Function.Params[i], param)
+		}
+
+		if fs.String() != tt.expectedString {
+			t.Errorf("fs.String() wrong. expected=%q, got=%q",
+				tt.expectedString, fs.String())
+		}
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	input := `let f = fn(x) { x }; fn(y) { y }; impl P { fn m(self) { self } }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Stmts[0].(*ast.LetStmt)
+	if name := let.Value.(*ast.FunctionLiteral).Name; name != "f" {
+		t.Errorf("let-bound function name is not %q. got=%q", "f", name)
+	}
+
+	exp := program.Stmts[1].(*ast.ExpStmt)
+	if name := exp.Exp.(*ast.FunctionLiteral).Name; name != "" {
+		t.Errorf("anonymous function has name %q", name)
+	}
+
+	impl := program.Stmts[2].(*ast.ImplStmt)
+	if _, ok := impl.Body.Stmts[0].(*ast.FunctionStmt); !ok {
+		t.Errorf("impl body is not ast.FunctionStmt. got=%T", impl.Body.Stmts[0])
+	}
+}
+
+func TestSpawnExp(t *testing.T) {
+	l := lexer.New(`spawn fib(n - 1)`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Stmts[0].(*ast.ExpStmt)
+	exp, ok := stmt.Exp.(*ast.SpawnExp)
+	if !ok {
+		t.Fatalf("stmt.Exp is not ast.SpawnExp. got=%T", stmt.Exp)
+	}
+
+	testIdentifier(t, exp.Call.Function, "fib")
+	if exp.String() != "spawn fib((n - 1))" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+}
+
+func TestSelectExp(t *testing.T) {
+	input := `select { recv(a) as v => v + 1, send(b, 2) => { 0 } _ => null }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Stmts[0].(*ast.ExpStmt)
+	exp, ok := stmt.Exp.(*ast.SelectExp)
+	if !ok {
+		t.Fatalf("stmt.Exp is not ast.SelectExp. got=%T", stmt.Exp)
+	}
+
+	expected := []struct {
+		comm    string
+		binding string
+		body    string
+	}{
+		{"recv(a)", "v", "(v + 1)"},
+		{"send(b, 2)", "", "0"},
+		{"", "", "null"},
+	}
+
+	if len(exp.Cases) != len(expected) {
+		t.Fatalf("len(exp.Cases) is not %d. got=%d", len(expected), len(exp.Cases))
+	}
+
+	for i, tt := range expected {
+		c := exp.Cases[i]
+
+		comm := ""
+		if c.Comm != nil {
+			comm = c.Comm.String()
+		}
+		if comm != tt.comm {
+			t.Errorf("wrong comm in case %d. expected=%q, got=%q", i, tt.comm, comm)
+		}
+
+		binding := ""
+		if c.Binding != nil {
+			binding = c.Binding.Value
+		}
+		if binding != tt.binding {
+			t.Errorf("wrong binding in case %d. expected=%q, got=%q", i, tt.binding, binding)
+		}
+
+		if c.Body.String() != tt.body {
+			t.Errorf("wrong body in case %d. expected=%q, got=%q", i, tt.body, c.Body.String())
+		}
+	}
+}
+
+func TestConcurrencyErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"spawn 5", "spawn needs a call"},
+		{"select { len(c) => 1 }", "select case must be recv(ch), send(ch, value) or _"},
+		{"select { recv(c) 1 }", "expected next token to be =>, got=INT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expectedError {
+			t.Errorf("expected error %q. got=%q", tt.expectedError, errors)
+		}
+	}
+}
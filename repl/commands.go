@@ -0,0 +1,221 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mdaisuke/monk/lexer"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/token"
+)
+
+// command is a REPL command, entered as :name followed by its argument.
+type command struct {
+	usage string
+	help  string
+	// run runs the command with arg, and reports whether the REPL should
+	// exit.
+	run func(s *session, arg string) (exit bool)
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help": {"", "list the commands", (*session).help},
+		"tokens": {"<src>", "show the tokens of src", func(s *session, arg string) bool {
+			l := lexer.New(arg)
+			for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+				fmt.Fprintf(s.out, "%-10s %s\n", tok.Type, tok.Literal)
+			}
+			return false
+		}},
+		"ast": {"<src>", "show the syntax tree of src", func(s *session, arg string) bool {
+			if program, ok := s.parse(arg); ok {
+				for _, stmt := range program.Stmts {
+					dumpNode(s.out, "", stmt)
+				}
+			}
+			return false
+		}},
+		"env": {"", "list the bindings of the session", func(s *session, arg string) bool {
+			for _, name := range s.env.Names() {
+				if !isIdent(name) {
+					continue
+				}
+				val, _ := s.env.Get(name)
+				fmt.Fprintf(s.out, "%s = %s\n", name, val.Inspect())
+			}
+			return false
+		}},
+		"type": {"<expr>", "show the type of the value of expr", func(s *session, arg string) bool {
+			o := s.eval(arg)
+			if o == nil || isError(o) {
+				return s.print(o)
+			}
+			fmt.Fprintln(s.out, typeOf(o))
+			return false
+		}},
+		"load": {"<file>", "run file in the session", func(s *session, arg string) bool {
+			b, err := os.ReadFile(arg)
+			if err != nil {
+				fmt.Fprintf(s.out, "could not load: %s\n", err)
+				return false
+			}
+			if o := s.eval(string(b)); isError(o) {
+				return s.print(o)
+			}
+			return false
+		}},
+		"reset": {"", "forget all bindings", func(s *session, arg string) bool {
+			s.reset()
+			return false
+		}},
+		"save": {"<file>", "save the history and bindings of the session to file", func(s *session, arg string) bool {
+			lost, err := s.save(arg)
+			if err != nil {
+				fmt.Fprintf(s.out, "could not save: %s\n", err)
+				return false
+			}
+			if len(lost) > 0 {
+				fmt.Fprintf(s.out, "could not save %s\n", strings.Join(lost, ", "))
+			}
+			return false
+		}},
+		"restore": {"<file>", "replace the session with the one saved to file", func(s *session, arg string) bool {
+			bound, err := s.restore(arg)
+			if err != nil {
+				fmt.Fprintf(s.out, "could not restore: %s\n", err)
+				return false
+			}
+			fmt.Fprintf(s.out, "restored %d bindings\n", bound)
+			return false
+		}},
+		"time": {"<expr>", "evaluate expr and show how long it took", func(s *session, arg string) bool {
+			start := time.Now()
+			o := s.eval(arg)
+			took := time.Since(start)
+
+			if s.print(o) {
+				return true
+			}
+			fmt.Fprintf(s.out, "took %s\n", took)
+			return false
+		}},
+	}
+}
+
+func (s *session) help(arg string) bool {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := commands[name]
+		fmt.Fprintf(s.out, "  %-16s %s\n", ":"+strings.TrimSpace(name+" "+c.usage), c.help)
+	}
+	return false
+}
+
+// runCommand runs the command line src, :name followed by its argument,
+// and reports whether the REPL should exit.
+func (s *session) runCommand(src string) bool {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(src), ":"), " ")
+	c, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "unknown command :%s, see :help\n", name)
+		return false
+	}
+
+	arg = strings.TrimSpace(arg)
+	if c.usage != "" && arg == "" {
+		fmt.Fprintf(s.out, "usage: :%s %s\n", name, c.usage)
+		return false
+	}
+	return c.run(s, arg)
+}
+
+func isCommand(src string) bool {
+	return strings.HasPrefix(strings.TrimSpace(src), ":")
+}
+
+func isError(o obj.Obj) bool {
+	return o != nil && o.Type() == obj.ERROR_OBJ
+}
+
+// isIdent reports whether name can be written in a script. Names bound
+// by the evaluator for its own use, like the Runtime of an evaluation,
+// cannot.
+func isIdent(name string) bool {
+	l := lexer.New(name)
+	tok := l.NextToken()
+	return tok.Type == token.IDENT && tok.Literal == name
+}
+
+// typeOf describes the type of o, naming the struct or enum of values of
+// user-defined types.
+func typeOf(o obj.Obj) string {
+	switch o := o.(type) {
+	case *obj.Struct:
+		return fmt.Sprintf("%s (%s)", o.Type(), o.StructType.Name)
+	case *obj.Variant:
+		return fmt.Sprintf("%s (%s.%s)", o.Type(), o.VariantType.Enum.Name, o.VariantType.Name)
+	default:
+		return string(o.Type())
+	}
+}
+
+var tokenType = reflect.TypeOf(token.Token{})
+
+// dumpNode writes the tree of node, one node per line indented under its
+// parent. Fields holding other nodes become children, the rest show
+// inline.
+func dumpNode(w io.Writer, indent string, node interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(node))
+
+	type child struct {
+		name string
+		v    reflect.Value
+	}
+	var attrs []string
+	var children []child
+
+	for i := 0; i < v.NumField(); i++ {
+		f, fv := v.Type().Field(i), v.Field(i)
+		switch {
+		case !f.IsExported() || f.Type == tokenType:
+		case fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface:
+			if !fv.IsNil() {
+				children = append(children, child{f.Name, fv})
+			}
+		case fv.Kind() == reflect.Slice && isRef(f.Type.Elem()):
+			for j := 0; j < fv.Len(); j++ {
+				children = append(children, child{fmt.Sprintf("%s[%d]", f.Name, j), fv.Index(j)})
+			}
+		default:
+			attrs = append(attrs, fmt.Sprintf("%s=%v", f.Name, fv.Interface()))
+		}
+	}
+
+	fmt.Fprintf(w, "%s%s", indent, v.Type().Name())
+	if len(attrs) > 0 {
+		fmt.Fprintf(w, " %s", strings.Join(attrs, " "))
+	}
+	fmt.Fprintln(w)
+
+	for _, c := range children {
+		fmt.Fprintf(w, "%s  %s:\n", indent, c.name)
+		dumpNode(w, indent+"    ", c.v.Interface())
+	}
+}
+
+func isRef(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface
+}
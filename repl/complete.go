@@ -0,0 +1,111 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mdaisuke/monk/eval"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/token"
+)
+
+// complete returns the completions of the word that line ends with, and
+// the offset in line where that word starts. After a dot, the word is
+// completed with the members of the value bound before it; at the start
+// of the line, after a colon, with the commands; elsewhere, with the
+// keywords, the names bound in the session and the builtins.
+func (s *session) complete(line string) (start int, candidates []string) {
+	start = len(line)
+	for start > 0 && isLetter(line[start-1]) {
+		start--
+	}
+	prefix := line[start:]
+
+	var names []string
+	switch {
+	case start > 0 && line[start-1] == '.':
+		recv := start - 1
+		for recv > 0 && isLetter(line[recv-1]) {
+			recv--
+		}
+		if o, ok := s.env.Get(line[recv : start-1]); ok {
+			names = members(o)
+		}
+	case strings.TrimSpace(line[:start]) == ":":
+		for name := range commands {
+			names = append(names, name)
+		}
+	default:
+		names = token.Keywords()
+		for env := s.env; env != nil; env = env.Outer() {
+			for _, name := range env.Names() {
+				if isIdent(name) {
+					names = append(names, name)
+				}
+			}
+		}
+		for name := range eval.DefaultBuiltins() {
+			if isIdent(name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+
+	return start, candidates
+}
+
+// members returns the names that can follow o and a dot: the exports of a
+// module, the variants of an enum, and the fields and methods of other
+// values.
+func members(o obj.Obj) []string {
+	switch o := o.(type) {
+	case *obj.Module:
+		var names []string
+		for _, name := range o.Env.Names() {
+			if isIdent(name) {
+				names = append(names, name)
+			}
+		}
+		return names
+	case *obj.EnumType:
+		var names []string
+		for _, vt := range o.Variants {
+			names = append(names, vt.Name)
+		}
+		return names
+	case *obj.Struct:
+		return append(append([]string(nil), o.StructType.Fields...), eval.MethodNames(o)...)
+	case *obj.Variant:
+		return append(append([]string(nil), o.VariantType.Fields...), eval.MethodNames(o)...)
+	default:
+		return eval.MethodNames(o)
+	}
+}
+
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+}
+
+// commonPrefix returns the longest prefix all of names share.
+func commonPrefix(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	prefix := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,306 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory bounds the lines of history kept.
+const maxHistory = 1000
+
+// editor reads lines from a terminal, letting the user edit them and
+// recall earlier ones. It supports the usual Emacs keys, arrows, Home,
+// End, Delete and Tab for completion.
+type editor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// raw puts the terminal in raw mode for the time a line is read.
+	raw func() (restore func(), err error)
+	// complete, if set, returns the completions of the word the line
+	// before the cursor ends with, and where that word starts.
+	complete func(line string) (start int, candidates []string)
+
+	history  []string
+	histFile string // where history persists, if anywhere
+}
+
+func newEditor(in io.Reader, out io.Writer, raw func() (func(), error)) *editor {
+	return &editor{in: bufio.NewReader(in), out: out, raw: raw}
+}
+
+// historyPath returns the file history persists in: $MONK_HISTORY, or
+// .monk_history in the home directory.
+func historyPath() string {
+	if path := os.Getenv("MONK_HISTORY"); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".monk_history")
+}
+
+// loadHistory reads the history persisted in path, and makes AddHistory
+// append to it.
+func (e *editor) loadHistory(path string) {
+	e.histFile = path
+	if path == "" {
+		return
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+		os.WriteFile(path, []byte(strings.Join(e.history, "\n")+"\n"), 0o600)
+	}
+}
+
+func (e *editor) AddHistory(line string) {
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+
+	if e.histFile == "" {
+		return
+	}
+	f, err := os.OpenFile(e.histFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(f, line)
+	f.Close()
+}
+
+func (e *editor) Close() error { return nil }
+
+func ctrl(r rune) rune { return r & 0x1f }
+
+func (e *editor) ReadLine(prompt string) (string, error) {
+	restore, err := e.raw()
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	l := &line{prompt: prompt}
+	hist := len(e.history) // the entry shown, len(e.history) for the new line
+	var edited []rune      // the new line, while an entry is shown
+
+	recall := func(i int) {
+		if i < 0 || i > len(e.history) {
+			return
+		}
+		if hist == len(e.history) {
+			edited = l.buf
+		}
+		hist = i
+
+		if i == len(e.history) {
+			l.set(edited)
+		} else {
+			l.set([]rune(e.history[i]))
+		}
+	}
+
+	l.refresh(e.out)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			io.WriteString(e.out, "\r\n")
+			if err == io.EOF && len(l.buf) > 0 {
+				return string(l.buf), nil
+			}
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			return string(l.buf), nil
+		case ctrl('C'):
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupt
+		case ctrl('D'):
+			if len(l.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			l.delete()
+		case '\t':
+			e.completeWord(l)
+		case 127, ctrl('H'):
+			l.backspace()
+		case ctrl('A'):
+			l.pos = 0
+		case ctrl('E'):
+			l.pos = len(l.buf)
+		case ctrl('B'):
+			l.move(-1)
+		case ctrl('F'):
+			l.move(1)
+		case ctrl('K'):
+			l.buf = l.buf[:l.pos]
+		case ctrl('U'):
+			l.buf = l.buf[l.pos:]
+			l.pos = 0
+		case ctrl('W'):
+			l.deleteWord()
+		case ctrl('L'):
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case ctrl('P'):
+			recall(hist - 1)
+		case ctrl('N'):
+			recall(hist + 1)
+		case 27:
+			switch e.escape() {
+			case "A":
+				recall(hist - 1)
+			case "B":
+				recall(hist + 1)
+			case "C":
+				l.move(1)
+			case "D":
+				l.move(-1)
+			case "H", "1~", "7~":
+				l.pos = 0
+			case "F", "4~", "8~":
+				l.pos = len(l.buf)
+			case "3~":
+				l.delete()
+			}
+		default:
+			if r >= ' ' {
+				l.insert(r)
+			}
+		}
+
+		l.refresh(e.out)
+	}
+}
+
+// completeWord completes the word before the cursor as far as its
+// candidates agree. When that adds nothing, it lists them.
+func (e *editor) completeWord(l *line) {
+	if e.complete == nil {
+		return
+	}
+
+	before := string(l.buf[:l.pos])
+	start, candidates := e.complete(before)
+	if len(candidates) == 0 {
+		return
+	}
+
+	word := before[start:]
+	if completion := commonPrefix(candidates); len(completion) > len(word) {
+		for _, r := range completion[len(word):] {
+			l.insert(r)
+		}
+		return
+	}
+
+	if len(candidates) > 1 {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+// escape reads the rest of an escape sequence, such as the "[A" sent by
+// the up arrow, and returns its final part: "A" for it, or "3~" for
+// Delete. Unknown sequences give "".
+func (e *editor) escape() string {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != '[' && r != 'O' {
+		return ""
+	}
+
+	var seq []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq = append(seq, r)
+		if r < '0' || r > '9' {
+			return string(seq)
+		}
+	}
+}
+
+// line is the line being edited, with the cursor before buf[pos].
+type line struct {
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+func (l *line) set(buf []rune) {
+	l.buf = append([]rune(nil), buf...)
+	l.pos = len(l.buf)
+}
+
+func (l *line) insert(r rune) {
+	l.buf = append(l.buf[:l.pos], append([]rune{r}, l.buf[l.pos:]...)...)
+	l.pos++
+}
+
+func (l *line) move(n int) {
+	if pos := l.pos + n; pos >= 0 && pos <= len(l.buf) {
+		l.pos = pos
+	}
+}
+
+func (l *line) backspace() {
+	if l.pos > 0 {
+		l.buf = append(l.buf[:l.pos-1], l.buf[l.pos:]...)
+		l.pos--
+	}
+}
+
+func (l *line) delete() {
+	if l.pos < len(l.buf) {
+		l.buf = append(l.buf[:l.pos], l.buf[l.pos+1:]...)
+	}
+}
+
+// deleteWord deletes the word before the cursor, with the spaces between
+// them.
+func (l *line) deleteWord() {
+	start := l.pos
+	for start > 0 && l.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && l.buf[start-1] != ' ' {
+		start--
+	}
+
+	l.buf = append(l.buf[:start], l.buf[l.pos:]...)
+	l.pos = start
+}
+
+// refresh redraws the line and puts the cursor back in place.
+func (l *line) refresh(out io.Writer) {
+	fmt.Fprintf(out, "\r%s%s\x1b[K", l.prompt, string(l.buf))
+	if n := len(l.buf) - l.pos; n > 0 {
+		fmt.Fprintf(out, "\x1b[%dD", n)
+	}
+}
@@ -0,0 +1,107 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// errInterrupt is returned by ReadLine when the user presses Ctrl-C.
+var errInterrupt = errors.New("interrupt")
+
+// lineReader reads the lines the user enters.
+type lineReader interface {
+	// ReadLine shows prompt and reads a line, without its newline. It
+	// returns io.EOF at the end of input.
+	ReadLine(prompt string) (string, error)
+	// AddHistory records line for the user to recall.
+	AddHistory(line string)
+	Close() error
+}
+
+// newLineReader returns an editor when in and out are a terminal, and
+// reads in line by line otherwise.
+func newLineReader(in io.Reader, out io.Writer) lineReader {
+	fin, ok := in.(*os.File)
+	fout, ok2 := out.(*os.File)
+	if ok && ok2 && isTerminal(fin.Fd()) && isTerminal(fout.Fd()) {
+		e := newEditor(fin, fout, func() (func(), error) { return makeRaw(fin.Fd()) })
+		e.loadHistory(historyPath())
+		return e
+	}
+
+	return &scanReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+type scanReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scanReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *scanReader) AddHistory(line string) {}
+func (r *scanReader) Close() error           { return nil }
+
+// readInput reads lines until they make up complete input, showing
+// CONT_PROMPT for every line after the first. Ctrl-C discards the lines
+// read so far.
+func readInput(lines lineReader) (string, error) {
+	var input []string
+	prompt := PROMPT
+
+	for {
+		line, err := lines.ReadLine(prompt)
+		if err == io.EOF && len(input) > 0 {
+			// Let the parser report what is missing.
+			return strings.Join(input, "\n"), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if strings.TrimSpace(line) != "" {
+			lines.AddHistory(line)
+		}
+
+		input = append(input, line)
+		src := strings.Join(input, "\n")
+		if !incomplete(src) {
+			return src, nil
+		}
+		prompt = CONT_PROMPT
+	}
+}
+
+// incomplete reports whether src ends inside a string or with brackets,
+// braces or parentheses left open, so that more lines must follow.
+func incomplete(src string) bool {
+	depth := 0
+	inString := false
+
+	for _, ch := range src {
+		switch {
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '(' || ch == '[' || ch == '{':
+			depth++
+		case ch == ')' || ch == ']' || ch == '}':
+			depth--
+		}
+	}
+
+	return inString || depth > 0
+}
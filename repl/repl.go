@@ -1,44 +1,54 @@
 package repl
 
 import (
-	"bufio"
-	"fmt"
 	"io"
-
-	"github.com/mdaisuke/monk/eval"
-	"github.com/mdaisuke/monk/lexer"
-	"github.com/mdaisuke/monk/obj"
-	"github.com/mdaisuke/monk/parser"
+	"os"
 )
 
 const PROMPT = ">> "
 
+// CONT_PROMPT is shown for the lines of input after the first.
+const CONT_PROMPT = ".. "
+
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := obj.NewEnv()
+	StartSession(in, out, "")
+}
 
-	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
-		}
+// StartSession is like Start, but restores the session saved to path, if
+// any, and saves it there on exit.
+func StartSession(in io.Reader, out io.Writer, path string) {
+	lines := newLineReader(in, out)
+	defer lines.Close()
+	s := newSession(out)
+	if e, ok := lines.(*editor); ok {
+		e.complete = s.complete
+	}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+	if path != "" {
+		if _, err := os.Stat(path); err == nil {
+			s.runCommand(":restore " + path)
+		}
+		defer s.runCommand(":save " + path)
+	}
 
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+	for {
+		src, err := readInput(lines)
+		if err == errInterrupt {
 			continue
 		}
+		if err != nil {
+			return
+		}
+		s.history = append(s.history, src)
 
-		evaluated := eval.Eval(program, env)
-		if evaluated != nil {
-			program.String()
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+		var exit bool
+		if isCommand(src) {
+			exit = s.runCommand(src)
+		} else {
+			exit = s.print(s.eval(src))
+		}
+		if exit {
+			return
 		}
 	}
 }
@@ -0,0 +1,305 @@
+package repl
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mdaisuke/monk/obj"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 5;", false},
+		{"let f = fn(x) {", true},
+		{"let f = fn(x) {\n  x + 1\n};", false},
+		{"[1, 2,", true},
+		{"add(1,", true},
+		{`"abc`, true},
+		{`"a{b"`, false},
+		{`let s = "}" + "{`, true},
+		{"}", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := incomplete(tt.input); got != tt.expected {
+			t.Errorf("incomplete(%q) wrong. expected=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+add(1,
+  2)
+"multi
+line"
+let x = ;
+exit()
+puts("not reached")
+`
+	expected := ">> .. .. >> .. 3\n>> .. multi\nline\n>> \tno prefix parse function for ; found\n>> "
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func noRaw() (func(), error) { return func() {}, nil }
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected []string
+	}{
+		{"abc\r", []string{"abc"}},
+		{"abc\x7f\x7fd\r", []string{"ad"}},
+		{"bc\x01a\x05d\r", []string{"abcd"}},
+		{"ac\x1b[Db\x1b[C!\r", []string{"abc!"}},
+		{"abc\x1b[H\x1b[3~\r", []string{"bc"}},
+		{"abcd\x02\x02\x0b\r", []string{"ab"}},
+		{"abcd\x02\x02\x15\r", []string{"cd"}},
+		{"let x = 1\x17\x172\r", []string{"let x 2"}},
+		{"one\rtwo\r\x1b[A\x1b[A\r", []string{"one", "two", "one"}},
+		{"one\rtwo\r\x10\x10\x0e\r", []string{"one", "two", "two"}},
+		{"one\rdraft\x1b[A\x1b[B\r", []string{"one", "draft"}},
+		{"abc\x03x\r", []string{"", "x"}},
+		{"ab\x04\r", []string{"ab"}},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := newEditor(strings.NewReader(tt.keys), &out, noRaw)
+
+		var got []string
+		for {
+			line, err := e.ReadLine(PROMPT)
+			if errors.Is(err, errInterrupt) {
+				got = append(got, "")
+				continue
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%q: unexpected error: %s", tt.keys, err)
+			}
+			got = append(got, line)
+			e.AddHistory(line)
+		}
+
+		if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+			t.Errorf("%q: wrong lines. expected=%q, got=%q", tt.keys, tt.expected, got)
+		}
+	}
+}
+
+func TestEditorHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(path, []byte("old\n"), 0o600)
+
+	e := newEditor(strings.NewReader("new\r\x1b[A\x1b[A\r"), io.Discard, noRaw)
+	e.loadHistory(path)
+
+	line, _ := e.ReadLine(PROMPT)
+	e.AddHistory(line)
+	if line, _ := e.ReadLine(PROMPT); line != "old" {
+		t.Errorf("wrong recalled line. expected=%q, got=%q", "old", line)
+	}
+
+	b, _ := os.ReadFile(path)
+	if string(b) != "old\nnew\n" {
+		t.Errorf("wrong history file. got=%q", b)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "lib.mk")
+	os.WriteFile(script, []byte("let double = fn(x) { x * 2 };\nlet loaded = double(21);\n"), 0o644)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":tokens let x = 5;", "LET        let\nIDENT      x\n=          =\nINT        5\n;          ;\n"},
+		{":ast -a", "ExpStmt\n  Exp:\n    PrefixExp Op=-\n      Right:\n        Identifier Value=a\n"},
+		{":ast let = 1", "\texpected next token to be IDENT, got== instead\n\tno prefix parse function for = found\n"},
+		{"let b = 2\nlet a = 1\n:env", "a = 1\nb = 2\n"},
+		{"fn g() { yield 1 } let it = g();\n:env", "g = fn g() {\nyield 1\n}\nit = iterator\n"},
+		{":type 1 + 1", "INTEGER\n"},
+		{"struct P { x }\n:type P(1)", "STRUCT (P)\n"},
+		{"enum E { A(x) }\n:type E.A(1)", "VARIANT (E.A)\n"},
+		{":type 1 + true", "ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+		{":load " + script + "\nloaded", "42\n"},
+		{":load " + script + ".missing", "could not load: open " + script + ".missing: no such file or directory\n"},
+		{"let a = 1\n:reset\n:env\na", "ERROR: identifier not found: a\n"},
+		{":type", "usage: :type <expr>\n"},
+		{":what", "unknown command :what, see :help\n"},
+		{":time exit(1)\n1", ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input+"\n"), &out)
+
+		got := strings.ReplaceAll(out.String(), PROMPT, "")
+		if got != tt.expected {
+			t.Errorf("%q: wrong output. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestTimeCommand(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(":time 6 * 7\n"), &out)
+
+	if !strings.HasPrefix(out.String(), PROMPT+"42\ntook ") {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+func TestComplete(t *testing.T) {
+	s := newSession(io.Discard)
+	s.eval(`
+	import "strings";
+	struct Point { x, y }
+	impl Point { fn norm(self) { self.x * self.x + self.y * self.y } }
+	enum Shape { Circle(r), Square(side) }
+	let p = Point(1, 2);
+	let printer = 1;
+	let name = "monk";
+	`)
+
+	tests := []struct {
+		line               string
+		expectedStart      int
+		expectedCandidates []string
+	}{
+		{"pri", 0, []string{"printer"}},
+		{"let q = p", 8, []string{"p", "printer"}},
+		{"fa", 0, []string{"false"}},
+		{"le", 0, []string{"len", "let"}},
+		{"p.", 2, []string{"norm", "x", "y"}},
+		{"p.n", 2, []string{"norm"}},
+		{"Shape.S", 6, []string{"Square"}},
+		{"strings.spl", 8, []string{"split"}},
+		{"name.up", 5, []string{"upper"}},
+		{"nothing.x", 8, nil},
+		{":re", 1, []string{"reset", "restore"}},
+		{"zzz", 0, nil},
+	}
+
+	for _, tt := range tests {
+		start, candidates := s.complete(tt.line)
+		if start != tt.expectedStart {
+			t.Errorf("%q: wrong start. expected=%d, got=%d", tt.line, tt.expectedStart, start)
+		}
+		if strings.Join(candidates, " ") != strings.Join(tt.expectedCandidates, " ") {
+			t.Errorf("%q: wrong candidates. expected=%q, got=%q", tt.line, tt.expectedCandidates, candidates)
+		}
+	}
+}
+
+func TestEditorComplete(t *testing.T) {
+	complete := func(line string) (int, []string) {
+		return 0, []string{"print", "printer"}
+	}
+
+	var out bytes.Buffer
+	e := newEditor(strings.NewReader("pr\t\t\r"), &out, noRaw)
+	e.complete = complete
+
+	line, _ := e.ReadLine(PROMPT)
+	if line != "print" {
+		t.Errorf("wrong line. expected=%q, got=%q", "print", line)
+	}
+	if !strings.Contains(out.String(), "\r\nprint  printer\r\n") {
+		t.Errorf("candidates not listed. got=%q", out.String())
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(`let rate = 3
+struct Point { x, y }
+let scale = fn(p) { Point(p.x * rate, p.y * rate) }
+let data = {"a": [1, true, null], 2: "two"}
+let rate = 4
+let origin = Point(0, 0)
+:save `+path+`
+`), &out)
+
+	if got := strings.ReplaceAll(out.String(), PROMPT, ""); got != "" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"scale(Point(1, 2))", "Point{x: 4, y: 8}"},
+		{"origin", "Point{x: 0, y: 0}"},
+		{`data["a"]`, "[1, true, null]"},
+		{"data[2]", "two"},
+		{"rate", "4"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(":restore "+path+"\n"+tt.input+"\n"), &out)
+
+		expected := "restored 5 bindings\n" + tt.expected + "\n"
+		if got := strings.ReplaceAll(out.String(), PROMPT, ""); got != expected {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", tt.input, expected, got)
+		}
+	}
+}
+
+func TestSaveUnsaveable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	s := newSession(io.Discard)
+	s.eval(`let x = 1; let s = "a"`)
+	s.env.Set("given", &obj.Builtin{})
+	s.env.Set("quote", &obj.String{Value: `say "hi"`})
+	lost, err := s.save(path)
+	if err != nil {
+		t.Fatalf("save failed: %s", err)
+	}
+	if strings.Join(lost, ",") != "given,quote" {
+		t.Errorf("wrong lost names. got=%q", lost)
+	}
+}
+
+func TestStartSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	StartSession(strings.NewReader("let x = 41\nfn inc(n) { n + 1 }\n"), io.Discard, path)
+
+	var out bytes.Buffer
+	StartSession(strings.NewReader("inc(x)\n"), &out, path)
+
+	if got := strings.ReplaceAll(out.String(), PROMPT, ""); got != "restored 2 bindings\n42\n" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+
+	b, _ := os.ReadFile(path)
+	if !strings.Contains(string(b), `"let x = 41"`) || !strings.Contains(string(b), `"inc(x)"`) {
+		t.Errorf("history not saved. got=%s", b)
+	}
+}
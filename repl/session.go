@@ -0,0 +1,237 @@
+package repl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mdaisuke/monk/ast"
+	"github.com/mdaisuke/monk/eval"
+	"github.com/mdaisuke/monk/lexer"
+	"github.com/mdaisuke/monk/obj"
+	"github.com/mdaisuke/monk/parser"
+)
+
+// session is the state of a REPL: the bindings made so far, the input
+// that made them and where output goes.
+type session struct {
+	env *obj.Env
+	out io.Writer
+
+	history []string       // the input entered, commands included
+	sources []string       // the input evaluated that bound names
+	defs    map[string]int // the source each name was last bound by
+}
+
+func newSession(out io.Writer) *session {
+	return &session{env: obj.NewEnv(), out: out, defs: make(map[string]int)}
+}
+
+// parse parses src, printing the errors it has, if any.
+func (s *session) parse(src string) (*ast.Program, bool) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil, false
+	}
+	return program, true
+}
+
+// eval evaluates src in the session, printing its parser errors, if any.
+// It returns nil when src does not parse. The names src binds are noted,
+// for save to tell which source to replay to bind them again.
+func (s *session) eval(src string) obj.Obj {
+	program, ok := s.parse(src)
+	if !ok {
+		return nil
+	}
+
+	before := make(map[string]obj.Obj)
+	for _, name := range s.env.Names() {
+		before[name], _ = s.env.Get(name)
+	}
+
+	result := eval.Eval(program, s.env)
+
+	bound := false
+	for _, name := range s.env.Names() {
+		if val, _ := s.env.Get(name); val != before[name] {
+			s.defs[name] = len(s.sources)
+			bound = true
+		}
+	}
+	if bound {
+		s.sources = append(s.sources, src)
+	}
+
+	return result
+}
+
+// print prints o, the value of an evaluation, and reports whether the
+// script asked to exit.
+func (s *session) print(o obj.Obj) (exit bool) {
+	if err, ok := o.(*obj.Error); ok && err.Kind == obj.EXIT {
+		return true
+	}
+	if o != nil {
+		io.WriteString(s.out, o.Inspect())
+		io.WriteString(s.out, "\n")
+	}
+	return false
+}
+
+func (s *session) reset() {
+	s.env = obj.NewEnv()
+	s.sources = nil
+	s.defs = make(map[string]int)
+}
+
+// savedSession is what save writes to a file, as JSON.
+type savedSession struct {
+	History []string `json:"history"`
+	// Sources are the input to replay to bind again the names whose
+	// values have no literal, such as functions.
+	Sources []string `json:"sources"`
+	// Bindings hold the other values, as literals.
+	Bindings map[string]string `json:"bindings"`
+}
+
+// save writes the history and bindings of the session to path. It returns
+// the names it could not save: those whose values have no literal and
+// were not bound by input of the session.
+func (s *session) save(path string) (lost []string, err error) {
+	saved := savedSession{History: s.history, Sources: []string{}, Bindings: make(map[string]string)}
+
+	replay := make(map[int]bool)
+	for _, name := range s.env.Names() {
+		if !isIdent(name) {
+			continue
+		}
+
+		val, _ := s.env.Get(name)
+		if src, ok := literal(val); ok {
+			saved.Bindings[name] = src
+		} else if i, ok := s.defs[name]; ok {
+			replay[i] = true
+		} else {
+			lost = append(lost, name)
+		}
+	}
+
+	indexes := make([]int, 0, len(replay))
+	for i := range replay {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		saved.Sources = append(saved.Sources, s.sources[i])
+	}
+
+	b, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return lost, os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// restore replaces the session with the one saved to path. The literal
+// bindings are made both before the sources are replayed, for them to
+// refer to, and after, as replaying may rebind them.
+func (s *session) restore(path string) (bound int, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var saved savedSession
+	if err := json.Unmarshal(b, &saved); err != nil {
+		return 0, fmt.Errorf("%s is not a saved session: %s", path, err)
+	}
+
+	s.reset()
+	s.history = append(saved.History, s.history...)
+
+	if err := s.bindLiterals(saved.Bindings); err != nil {
+		return 0, err
+	}
+	for _, src := range saved.Sources {
+		if o := s.eval(src); isError(o) {
+			s.print(o)
+		}
+	}
+	if err := s.bindLiterals(saved.Bindings); err != nil {
+		return 0, err
+	}
+
+	for _, name := range s.env.Names() {
+		if isIdent(name) {
+			bound++
+		}
+	}
+	return bound, nil
+}
+
+func (s *session) bindLiterals(bindings map[string]string) error {
+	for name, src := range bindings {
+		p := parser.New(lexer.New(src))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			return fmt.Errorf("bad value for %s: %s", name, strings.Join(p.Errors(), ", "))
+		}
+
+		s.env.Set(name, eval.Eval(program, obj.NewEnv()))
+		delete(s.defs, name)
+	}
+	return nil
+}
+
+// literal returns the source of a literal evaluating to a value equal to
+// o, if there is one.
+func literal(o obj.Obj) (string, bool) {
+	switch o := o.(type) {
+	case *obj.Integer:
+		// The literal of the least integer would overflow before its
+		// minus sign applies.
+		return strconv.FormatInt(o.Value, 10), o.Value != math.MinInt64
+	case *obj.Boolean:
+		return strconv.FormatBool(o.Value), true
+	case *obj.Null:
+		return "null", true
+	case *obj.String:
+		// Strings have no escapes.
+		return `"` + o.Value + `"`, !strings.Contains(o.Value, `"`)
+	case *obj.Array:
+		elems := []string{}
+		for _, e := range o.Elems {
+			src, ok := literal(e)
+			if !ok {
+				return "", false
+			}
+			elems = append(elems, src)
+		}
+		return "[" + strings.Join(elems, ", ") + "]", true
+	case *obj.Hash:
+		pairs := []string{}
+		for _, hk := range o.Keys {
+			pair := o.Pairs[hk]
+			key, ok := literal(pair.Key)
+			if !ok {
+				return "", false
+			}
+			value, ok := literal(pair.Value)
+			if !ok {
+				return "", false
+			}
+			pairs = append(pairs, key+": "+value)
+		}
+		return "{" + strings.Join(pairs, ", ") + "}", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package repl
+
+import "errors"
+
+// Without raw mode, input is read line by line, without editing.
+
+func isTerminal(fd uintptr) bool { return false }
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode is not supported")
+}
@@ -1,5 +1,7 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 const (
@@ -22,9 +24,19 @@ const (
 
 	EQ     = "=="
 	NOT_EQ = "!="
+	ARROW  = "=>"
+
+	PIPE    = "|>"
+	COMPOSE = ">>"
+
+	OPT_DOT     = "?."
+	OPT_BRACKET = "?["
+	NULLISH     = "??"
 
 	COMMA     = ","
 	SEMICOLON = ";"
+	DOT       = "."
+	COLON     = ":"
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -40,6 +52,21 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
+	FOR      = "FOR"
+	IN       = "IN"
+	YIELD    = "YIELD"
+	STRUCT   = "STRUCT"
+	WITH     = "WITH"
+	IMPL     = "IMPL"
+	ENUM     = "ENUM"
+	MATCH    = "MATCH"
+	IS       = "IS"
+	NULL     = "NULL"
+	SPAWN    = "SPAWN"
+	SELECT   = "SELECT"
 )
 
 var keywords = map[string]TokenType{
@@ -50,6 +77,21 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"import": IMPORT,
+	"export": EXPORT,
+	"as":     AS,
+	"for":    FOR,
+	"in":     IN,
+	"yield":  YIELD,
+	"struct": STRUCT,
+	"with":   WITH,
+	"impl":   IMPL,
+	"enum":   ENUM,
+	"match":  MATCH,
+	"is":     IS,
+	"null":   NULL,
+	"spawn":  SPAWN,
+	"select": SELECT,
 }
 
 type Token struct {
@@ -63,3 +105,13 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// Keywords returns the keywords of the language, sorted.
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}